@@ -0,0 +1,234 @@
+// Package archive keeps the sent reports on the server, compressed and with retention
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/g0rbe/vps-sentinel/internal/atomicfile"
+	"github.com/g0rbe/vps-sentinel/render"
+	"github.com/g0rbe/vps-sentinel/report"
+)
+
+// IDFormat is the format of the time in the IDs of the reports
+const IDFormat = "2006-01-02T15:04:05"
+
+// Extensions of the structured and the rendered report
+const (
+	jsonExt = ".json.gz"
+	textExt = ".txt.gz"
+)
+
+// Archive stores the reports in a directory.
+// Every report has a JSON and a text file, named after the time of the report.
+type Archive struct {
+	Dir    string
+	Keep   int           // Maximum number of reports, 0 for unlimited
+	MaxAge time.Duration // Maximum age of the reports, 0 for unlimited
+}
+
+// New returns an archive in the given directory
+func New(dir string, keep int, maxAge time.Duration) *Archive {
+	return &Archive{Dir: dir, Keep: keep, MaxAge: maxAge}
+}
+
+// writeGzip compresses the content to path
+func writeGzip(path string, content []byte) error {
+
+	var buf bytes.Buffer
+
+	w := gzip.NewWriter(&buf)
+
+	if _, err := w.Write(content); err != nil {
+		return fmt.Errorf("failed to compress %s: %s", path, err)
+	}
+
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to compress %s: %s", path, err)
+	}
+
+	return atomicfile.Write(path, buf.Bytes(), 0600)
+}
+
+// readGzip returns the decompressed content of path
+func readGzip(path string) ([]byte, error) {
+
+	f, err := os.Open(path)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %s", path, err)
+	}
+	defer f.Close()
+
+	r, err := gzip.NewReader(f)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress %s: %s", path, err)
+	}
+
+	content, err := ioutil.ReadAll(r)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress %s: %s", path, err)
+	}
+
+	return content, nil
+}
+
+// Save stores the report in both JSON and text format, then removes the old reports.
+// Returns the ID of the stored report.
+func (a *Archive) Save(r report.Report) (string, error) {
+
+	if err := os.MkdirAll(a.Dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create %s: %s", a.Dir, err)
+	}
+
+	id := r.Time.Format(IDFormat)
+
+	content, err := render.JSON(r)
+
+	if err != nil {
+		return "", fmt.Errorf("failed to render JSON report: %s", err)
+	}
+
+	if err := writeGzip(filepath.Join(a.Dir, id+jsonExt), content); err != nil {
+		return "", err
+	}
+
+	if err := writeGzip(filepath.Join(a.Dir, id+textExt), []byte(render.Text(r))); err != nil {
+		return "", err
+	}
+
+	return id, a.prune(r.Time)
+}
+
+// List returns the IDs of the stored reports, from the oldest to the newest
+func (a *Archive) List() ([]string, error) {
+
+	files, err := ioutil.ReadDir(a.Dir)
+
+	if os.IsNotExist(err) {
+		return []string{}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %s", a.Dir, err)
+	}
+
+	ids := make([]string, 0)
+
+	for _, f := range files {
+		if strings.HasSuffix(f.Name(), jsonExt) {
+			ids = append(ids, strings.TrimSuffix(f.Name(), jsonExt))
+		}
+	}
+
+	// IDFormat sorts lexically in time order
+	sort.Strings(ids)
+
+	return ids, nil
+}
+
+// prune removes the reports above Keep or older than MaxAge
+func (a *Archive) prune(now time.Time) error {
+
+	ids, err := a.List()
+
+	if err != nil {
+		return err
+	}
+
+	for i, id := range ids {
+
+		expired := a.Keep > 0 && i < len(ids)-a.Keep
+
+		if t, err := time.ParseInLocation(IDFormat, id, time.Local); err == nil && a.MaxAge > 0 {
+			expired = expired || now.Sub(t) > a.MaxAge
+		}
+
+		if !expired {
+			continue
+		}
+
+		for _, ext := range []string{jsonExt, textExt} {
+
+			path := filepath.Join(a.Dir, id+ext)
+
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to remove %s: %s", path, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Find returns the ID of the newest report which starts with prefix, eg.: 2020-05-01 or 2020-05-01T04.
+// A space can be used instead of the T, "latest" is the newest report.
+func (a *Archive) Find(prefix string) (string, error) {
+
+	ids, err := a.List()
+
+	if err != nil {
+		return "", err
+	}
+
+	if len(ids) == 0 {
+		return "", fmt.Errorf("no reports in %s", a.Dir)
+	}
+
+	if prefix == "latest" {
+		return ids[len(ids)-1], nil
+	}
+
+	prefix = strings.Replace(prefix, " ", "T", 1)
+
+	for i := len(ids) - 1; i >= 0; i-- {
+		if strings.HasPrefix(ids[i], prefix) {
+			return ids[i], nil
+		}
+	}
+
+	return "", fmt.Errorf("no report from %s", prefix)
+}
+
+// Load returns the structured report
+func (a *Archive) Load(id string) (report.Report, error) {
+
+	var r report.Report
+
+	content, err := readGzip(filepath.Join(a.Dir, id+jsonExt))
+
+	if err != nil {
+		return r, err
+	}
+
+	if err := json.Unmarshal(content, &r); err != nil {
+		return r, fmt.Errorf("failed to parse report %s: %s", id, err)
+	}
+
+	return r, nil
+}
+
+// Text returns the rendered report
+func (a *Archive) Text(id string) (string, error) {
+
+	content, err := readGzip(filepath.Join(a.Dir, id+textExt))
+
+	if err != nil {
+		return "", err
+	}
+
+	return string(content), nil
+}
+
+// JSON returns the structured report as it is stored
+func (a *Archive) JSON(id string) ([]byte, error) {
+	return readGzip(filepath.Join(a.Dir, id+jsonExt))
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/g0rbe/vps-sentinel/configparser"
+)
+
+// checkConfigCmd parses the configuration file and its drop-in files, and prints every problem, one per line.
+// With --effective, the merged configuration is printed first.
+func checkConfigCmd(args []string) int {
+
+	var opts options
+
+	fs := flag.NewFlagSet("check-config", flag.ExitOnError)
+	opts.register(fs)
+	effective := fs.Bool("effective", false, "print the configuration merged from the drop-in files and the environment")
+	fs.Parse(args)
+
+	if *effective {
+
+		text, err := configparser.Effective(opts.config)
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			return 1
+		}
+
+		fmt.Print(text)
+	}
+
+	conf, err := opts.loadConfig()
+
+	if errs, ok := err.(configparser.Errors); ok {
+
+		// Every problem has its file and line
+		for _, err := range errs {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+		}
+
+		return 1
+	} else if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", opts.config, err)
+		return 1
+	}
+
+	if *effective {
+		fmt.Printf("# OK, sections: %v\n", conf.ReportStructure)
+	} else {
+		fmt.Printf("%s: OK, sections: %v\n", opts.config, conf.ReportStructure)
+	}
+
+	return 0
+}
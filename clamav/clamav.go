@@ -1,81 +1,135 @@
 package clamav
 
 import (
+	"bytes"
+	"context"
 	"fmt"
-	"io/ioutil"
 	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/g0rbe/vps-sentinel/report"
 )
 
 // freshclam checks the status of the freshclam service.
 // If freshclam daemon is not running, then update the db manually
-func freschclam() error {
+func freschclam(ctx context.Context) error {
 
-	cmdCheck := exec.Command("/bin/systemctl", "-q", "is-active", "clamav-freshclam.service")
+	cmdCheck := exec.CommandContext(ctx, "/bin/systemctl", "-q", "is-active",
+		"clamav-freshclam.service")
 
 	if err := cmdCheck.Run(); err == nil {
 		return nil
 	}
 
-	cmdUpdate := exec.Command("/usr/bin/freshclam", "--quiet")
+	cmdUpdate := exec.CommandContext(ctx, "/usr/bin/freshclam", "--quiet")
 
 	if out, err := cmdUpdate.CombinedOutput(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
 		return fmt.Errorf("failed to run freshclam: %s, %s", out, err)
 	}
 
 	return nil
 }
 
-// RunClamAV runs clamscan on the given path
-// Returns the report
-func RunClamAV(path string) (string, error) {
+// parseOutput parses the output of clamscan.
+// Infected files goes to the table, the scan summary goes to the fields.
+func parseOutput(out string) report.Section {
 
-	// Update ClamAV-s datavase
-	if err := freschclam(); err != nil {
-		return "", fmt.Errorf("faile to update database with freshclam: %s", err)
-	}
+	var section report.Section
 
-	// Needed if someone wants to scan the whole system ("/")
-	excludeDirs := "--exclude-dir=\"^/sys\" --exclude-dir=\"^/proc\" --exclude-dir=\"^/dev\""
+	section.Table = report.NewTable(
+		report.Column{Name: "File", Type: report.String},
+		report.Column{Name: "Signature", Type: report.String})
+	section.Table.Key = []string{"File", "Signature"}
 
-	command := "/usr/bin/clamscan -i -r " + excludeDirs + " " + path
+	inSummary := false
 
-	cmd := exec.Command("sh", "-c", command)
+	for _, line := range strings.Split(out, "\n") {
 
-	stdOut, err := cmd.StdoutPipe()
-	if err != nil {
-		return "", fmt.Errorf("failed to gte stdout pipe: %s", err)
-	}
+		line = strings.TrimSpace(line)
 
-	stdErr, err := cmd.StderrPipe()
-	if err != nil {
-		return "", fmt.Errorf("failed to get stderr pipe: %s", err)
-	}
+		switch {
+		case line == "":
+			continue
+		case strings.Contains(line, "SCAN SUMMARY"):
+			inSummary = true
+		case inSummary:
+			parts := strings.SplitN(line, ":", 2)
+			if len(parts) != 2 {
+				continue
+			}
+
+			label := strings.TrimSpace(parts[0])
+			valueStr := strings.TrimSpace(parts[1])
+
+			var value interface{} = valueStr
+
+			if num, err := strconv.Atoi(valueStr); err == nil {
+				value = num
+			}
 
-	if err = cmd.Start(); err != nil {
-		return "", fmt.Errorf("failed to start ClamAV: %s", err)
+			section.Fields = append(section.Fields, report.Field{
+				Key:   strings.ReplaceAll(strings.ToLower(label), " ", "_"),
+				Label: label,
+				Value: value})
+		case strings.HasSuffix(line, " FOUND"):
+			// Format: <file>: <signature> FOUND
+			sep := strings.LastIndex(line, ": ")
+			if sep == -1 {
+				continue
+			}
+
+			file := line[:sep]
+			signature := strings.TrimSuffix(line[sep+2:], " FOUND")
+
+			section.Table.Append(file, signature)
+		}
 	}
 
-	cmdErr, err := ioutil.ReadAll(stdErr)
-	if err != nil {
-		return "", fmt.Errorf("failed to read from cmd's stderr: %s", err)
+	return section
+}
+
+// RunClamAV runs clamscan on the given path
+// Returns the infected files and the summary of the scan.
+// clamscan is killed if ctx is done before the scan is finished.
+func RunClamAV(ctx context.Context, path string) (report.Section, error) {
+
+	var section report.Section
+
+	// Update ClamAV-s datavase
+	if err := freschclam(ctx); err != nil {
+		return section, fmt.Errorf("faile to update database with freshclam: %s", err)
 	}
 
-	cmdOut, err := ioutil.ReadAll(stdOut)
-	if err != nil {
-		return "", fmt.Errorf("failed to read form cmd's stdout: %s", err)
+	// Needed if someone wants to scan the whole system ("/")
+	// Run clamscan without shell, so the context kills clamscan itself, not just the shell
+	cmd := exec.CommandContext(ctx, "/usr/bin/clamscan", "-i", "-r",
+		"--exclude-dir=^/sys", "--exclude-dir=^/proc", "--exclude-dir=^/dev", path)
+
+	var stdOut, stdErr bytes.Buffer
+
+	cmd.Stdout = &stdOut
+	cmd.Stderr = &stdErr
+
+	if err := cmd.Start(); err != nil {
+		return section, fmt.Errorf("failed to start ClamAV: %s", err)
 	}
 
-	if err = cmd.Wait(); err != nil {
+	if err := cmd.Wait(); err != nil {
+		if ctx.Err() != nil {
+			return section, fmt.Errorf("scan of %s is interrupted: %s", path, ctx.Err())
+		}
+
 		if exitErr, ok := err.(*exec.ExitError); ok &&
 			exitErr.ExitCode() != 1 && exitErr.ExitCode() != 0 {
 
-			return "", fmt.Errorf("failed to scan %s: %s", path, cmdErr)
+			return section, fmt.Errorf("failed to scan %s: %s", path, stdErr.String())
 		}
 	}
 
-	var report string
-
-	report += string(cmdOut) + "\n\n"
-
-	return report, nil
+	return parseOutput(stdOut.String()), nil
 }
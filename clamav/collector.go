@@ -0,0 +1,44 @@
+package clamav
+
+import (
+	"context"
+
+	"github.com/g0rbe/vps-sentinel/collector"
+	"github.com/g0rbe/vps-sentinel/configparser"
+	"github.com/g0rbe/vps-sentinel/report"
+)
+
+// clamCollector is the "clamav" feature of the report
+type clamCollector struct{}
+
+func init() {
+	collector.Register(clamCollector{})
+}
+
+func (clamCollector) Name() string {
+	return "clamav"
+}
+
+func (clamCollector) Title() string {
+	return "ClamAV scan"
+}
+
+func (clamCollector) Section() string {
+	return "clamav"
+}
+
+// Collect runs a scan in every path of clamav->path
+func (clamCollector) Collect(ctx context.Context, conf configparser.Config) []report.Section {
+
+	sections := make([]report.Section, 0, len(conf.ClamAVPath))
+
+	for _, path := range conf.ClamAVPath {
+
+		section, err := RunClamAV(ctx, path)
+
+		sections = append(sections, collector.NewSection(
+			"clamav:"+path, "ClamAV scan in "+path, section, err))
+	}
+
+	return sections
+}
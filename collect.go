@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/g0rbe/vps-sentinel/archive"
+	"github.com/g0rbe/vps-sentinel/collector"
+	"github.com/g0rbe/vps-sentinel/configparser"
+	"github.com/g0rbe/vps-sentinel/report"
+	"github.com/g0rbe/vps-sentinel/rules"
+	"github.com/g0rbe/vps-sentinel/state"
+	"github.com/g0rbe/vps-sentinel/sysinfo"
+)
+
+// snapshotStore returns the store of the sections' last output
+func snapshotStore(conf configparser.Config) *state.Store {
+	return state.New(filepath.Join(conf.StatePath, "snapshots"))
+}
+
+// reportArchive returns the archive of the sent reports
+func reportArchive(conf configparser.Config) *archive.Archive {
+	return archive.New(filepath.Join(conf.StatePath, "reports"), conf.ArchiveKeep, conf.ArchiveMaxAge)
+}
+
+// collectReport runs the collectors in report->structure,
+// compares the result with the previous run and evaluates the rules.
+// The progress messages are written to progress.
+func collectReport(ctx context.Context, conf configparser.Config, progress io.Writer) report.Report {
+
+	r := report.Report{
+		Kind:     report.KindReport,
+		Host:     sysinfo.GetFqdn(),
+		Time:     time.Now(),
+		Sections: collector.Run(ctx, conf, progress)}
+
+	if err := snapshotStore(conf).Diff(&r); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to compare with the previous run: %s\n", err)
+	}
+
+	rules.Evaluate(&r, conf)
+
+	return r
+}
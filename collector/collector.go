@@ -0,0 +1,90 @@
+// Package collector defines the parts of the report and the registry of them
+package collector
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/g0rbe/vps-sentinel/configparser"
+	"github.com/g0rbe/vps-sentinel/report"
+)
+
+// Collector gathers informations for one feature in report->structure
+type Collector interface {
+	// Name returns the name used in report->structure
+	Name() string
+
+	// Title returns the human readable name of the collector
+	Title() string
+
+	// Section returns the section of the config file used by the collector, eg.: its timeout.
+	// The section is checked only if the collector is enabled.
+	// Empty string means that the collector has no settings.
+	Section() string
+
+	// Collect gathers the informations and returns the sections of the report
+	Collect(ctx context.Context, conf configparser.Config) []report.Section
+}
+
+// NewSection sets the identifiers of the section returned by a collector.
+// If err is not nil, the section is marked as failed.
+func NewSection(id, title string, section report.Section, err error) report.Section {
+
+	section.ID = id
+	section.Title = title
+	section.Status = report.StatusOK
+
+	if err != nil {
+		section.SetError(err)
+	}
+
+	return section
+}
+
+var registry = make(map[string]Collector)
+
+// Register makes a collector available in report->structure.
+// Register panics if a collector with the same name is already registered.
+func Register(c Collector) {
+
+	if _, ok := registry[c.Name()]; ok {
+		panic(fmt.Sprintf("collector already registered: %s", c.Name()))
+	}
+
+	registry[c.Name()] = c
+}
+
+// Get returns the collector registered with the given name
+func Get(name string) (Collector, bool) {
+
+	c, ok := registry[name]
+
+	return c, ok
+}
+
+// Names returns the sorted list of the registered collectors' name
+func Names() []string {
+
+	names := make([]string, 0, len(registry))
+
+	for name := range registry {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// Sections returns the section of the config file of every registered collector, by name
+func Sections() map[string]string {
+
+	sections := make(map[string]string, len(registry))
+
+	for name, c := range registry {
+		sections[name] = c.Section()
+	}
+
+	return sections
+}
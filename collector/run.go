@@ -0,0 +1,111 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/g0rbe/vps-sentinel/configparser"
+	"github.com/g0rbe/vps-sentinel/report"
+)
+
+// result is the outcome of one collector
+type result struct {
+	index    int
+	sections []report.Section
+}
+
+// collect runs one collector with its timeout.
+// If the collector does not finish in time, a single timed out section is returned
+// and the collector is left to stop on its own by the cancelled context.
+func collect(ctx context.Context, c Collector, conf configparser.Config, timeout time.Duration) []report.Section {
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	done := make(chan []report.Section, 1)
+
+	go func() {
+		done <- c.Collect(ctx, conf)
+	}()
+
+	select {
+	case sections := <-done:
+		return sections
+	case <-ctx.Done():
+		section := report.Section{ID: c.Name(), Title: c.Title(), Status: report.StatusTimeout}
+
+		if ctx.Err() == context.DeadlineExceeded {
+			section.Error = fmt.Sprintf("timed out after %s", timeout)
+		} else {
+			section.Error = ctx.Err().Error()
+		}
+
+		return []report.Section{section}
+	}
+}
+
+// Run runs the collectors in report->structure concurrently.
+// Every collector has its own timeout, see configparser.Config.Timeout.
+// The sections are returned in the order of report->structure.
+// The progress messages are written to progress.
+func Run(ctx context.Context, conf configparser.Config, progress io.Writer) []report.Section {
+
+	results := make(chan result, len(conf.ReportStructure))
+
+	for i, name := range conf.ReportStructure {
+
+		c, ok := Get(name)
+
+		if !ok {
+			results <- result{index: i, sections: []report.Section{{
+				ID: name, Title: name, Status: report.StatusError,
+				Error: "no such collector: " + name}}}
+			continue
+		}
+
+		go func(i int, c Collector) {
+
+			start := time.Now()
+
+			fmt.Fprintf(progress, "Collecting %s...\n", c.Name())
+
+			sections := collect(ctx, c, conf, conf.Timeout(c.Name()))
+
+			for j := range sections {
+
+				sections[j].Collector = c.Name()
+
+				if sections[j].Status != report.StatusOK {
+					fmt.Fprintf(progress, "Failed to get %s: %s\n",
+						sections[j].Title, sections[j].Error)
+				}
+			}
+
+			fmt.Fprintf(progress, "Finished %s in %s\n",
+				c.Name(), time.Since(start).Round(time.Millisecond))
+
+			results <- result{index: i, sections: sections}
+		}(i, c)
+	}
+
+	ordered := make([][]report.Section, len(conf.ReportStructure))
+
+	for range conf.ReportStructure {
+		r := <-results
+		ordered[r.index] = r.sections
+	}
+
+	sections := make([]report.Section, 0)
+
+	for _, s := range ordered {
+		sections = append(sections, s...)
+	}
+
+	return sections
+}
@@ -3,27 +3,113 @@ package configparser
 
 import (
 	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"gopkg.in/ini.v1"
+
+	"github.com/g0rbe/vps-sentinel/pgpmime"
+	"github.com/g0rbe/vps-sentinel/render"
+	"github.com/g0rbe/vps-sentinel/report"
+	"github.com/g0rbe/vps-sentinel/schedule"
 )
 
 // Config is the tructure to store configuration settings
 type Config struct {
-	ReportStructure []string
-	PortProtocol    []string
-	ProcessSort     string
-	ClamAVPath      []string
-	SSHLogPath      string
-	SSHParseFailed  bool
-	SSHMultiple     bool
-	NginxLogPath    string
-	SMTPServer      string
-	SMTPPort        int
-	SMTPUser        string
-	SMTPPassword    string
-	SMTPRecipient   string
+	ReportStructure  []string
+	ReportJSON       []string
+	ReportJSONFile   string
+	TextTemplate     string // Path of the user's text/template, empty for the built-in
+	HTMLTemplate     string // Path of the user's html/template, empty for the built-in
+	ReportTimeout    time.Duration
+	SectionTimeout   map[string]time.Duration // Overrides of report->timeout per section
+	CPUInterval      time.Duration            // Sampling interval of the CPU usage
+	PortProtocol     []string
+	ProcessSort      string
+	ClamAVPath       []string
+	SSHLogPath       string
+	SSHParseFailed   bool
+	SSHMultiple      bool
+	SSHFollow        bool
+	SSHAllowUsers    []string
+	SSHAllowNetworks []*net.IPNet
+	NginxLogPath     string
+	Notifiers        []string
+	SMTPServer       string
+	SMTPPort         int
+	SMTPUser         string
+	SMTPPassword     string
+	SMTPRoutes       []Route // The first one is the default route with every section
+	SMTPFormat       string
+	SMTPTransport    string // starttls, tls, none or sendmail
+	SMTPSendmail     string // Path of sendmail, if the transport is sendmail
+	SMTPFrom         string
+	SMTPCAFile       string   // CA bundle to verify the server, empty for the system's
+	SMTPServerName   string   // Expected name in the server's certificate
+	SMTPEncryptTo    []string // Public keys to encrypt the mails to, empty to not encrypt
+	SMTPSignKey      string   // Private key to sign the mails, empty to not sign
+	SMTPPassphrase   string   // Passphrase of the signing key
+	WebhookURL       string
+	WebhookPayload   string
+	WebhookHeaders   map[string]string
+	WebhookSecret    string
+	WebhookRetries   int
+	WebhookTimeout   time.Duration
+	SlackURL         string
+	SlackUsername    string
+	SlackChannel     string
+	SlackLimit       int
+	TelegramAPIURL   string
+	TelegramToken    string
+	TelegramChatID   string
+	TelegramLimit    int
+	StatePath        string
+	ArchiveEnabled   bool                 // Keep the reports in state->path/reports
+	ArchiveKeep      int                  // Maximum number of archived reports, 0 for unlimited
+	ArchiveMaxAge    time.Duration        // Maximum age of the archived reports, 0 for unlimited
+	DaemonReport     string               // Cron expression of the full report
+	DaemonCheck      string               // Cron expression of the checks, empty if disabled
+	DaemonSections   []string             // Sections of the checks
+	MetricsListen    string               // Address of the metrics endpoint, empty if disabled
+	MetricsTop       int                  // Number of processes in the metrics
+	MetricsSections  []string             // Sections collected on every scrape
+	MetricsRefresh   time.Duration        // Minimum age of the sections collected for a scrape
+	Thresholds       map[string]Threshold // Thresholds of the rules, by rule name
+	ClamAVSeverity   report.Severity
+}
+
+// Route is a group of recipients of the mail, who get only the sections of the route
+type Route struct {
+	Name     string
+	Sections []string // Sections of report->structure, nil for every section
+	To       []string
+	CC       []string
+	BCC      []string
+}
+
+// Threshold is the limits of a rule, values above them are warning or critical
+type Threshold struct {
+	Warning  float64
+	Critical float64
+}
+
+// thresholdRules are the rules in the rules section with a warning,critical threshold
+var thresholdRules = []string{"memory", "load", "cpu_steal", "cpu_iowait", "disk", "inodes", "ssh_failed", "nginx_5xx"}
+
+// Timeout returns the timeout of the given section in report->structure
+func (c Config) Timeout(section string) time.Duration {
+
+	if timeout, ok := c.SectionTimeout[section]; ok {
+		return timeout
+	}
+
+	return c.ReportTimeout
 }
 
 // sanitizeInput sanitize the input.
@@ -41,133 +127,740 @@ func sanitizeInput(input string) error {
 	return nil
 }
 
-// Parse used to parse and check the configurations in the gven config file
-func Parse(path string) (Config, error) {
+// Contains reports whether list contains value, eg.: a feature in Config.ReportStructure
+func Contains(list []string, value string) bool {
+
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parser reads the keys of the configuration file and collects every problem
+type parser struct {
+	cfg       *ini.File
+	positions map[string]position // See load
+	errs      Errors
+}
+
+// errorf records a problem of the key in the section
+func (p *parser) errorf(section, key, format string, args ...interface{}) {
+
+	pos, ok := p.positions[section+"->"+key]
+
+	if !ok {
+		pos = p.positions[section]
+	}
+
+	msg := fmt.Sprintf(format, args...)
+
+	// The value is not from the file
+	if _, ok := os.LookupEnv(envName(section, key)); ok {
+		pos = position{}
+		msg += " (from " + envName(section, key) + ")"
+	}
+
+	p.errs = append(p.errs, Error{
+		File:    pos.file,
+		Line:    pos.line,
+		Section: section,
+		Key:     key,
+		Message: msg})
+}
+
+// has reports whether the key is set in the section or in the environment
+func (p *parser) has(section, key string) bool {
+
+	if _, ok := os.LookupEnv(envName(section, key)); ok {
+		return true
+	}
+
+	return p.inFile(section, key)
+}
+
+// inFile reports whether the key is in the section of the files.
+// Unlike HasKey of ini, the keys of the parent section (eg.: smtp for smtp.route.x) are not inherited.
+func (p *parser) inFile(section, key string) bool {
+	return Contains(p.cfg.Section(section).KeyStrings(), key)
+}
+
+// value returns the value of the key with the environment override and the ${ENV} references applied.
+// Returns false if the key is not set.
+func (p *parser) value(section, key string) (string, bool) {
+
+	if value, ok := os.LookupEnv(envName(section, key)); ok {
+		return value, true
+	}
+
+	if !p.inFile(section, key) {
+		return "", false
+	}
+
+	value, err := expand(p.cfg.Section(section).Key(key).String())
+	if err != nil {
+		p.errorf(section, key, "%s", err)
+	}
+
+	return value, true
+}
+
+// str returns the value of the key, def if it is not set or empty
+func (p *parser) str(section, key, def string) string {
+
+	if value, ok := p.value(section, key); ok && value != "" {
+		return value
+	}
+
+	return def
+}
+
+// required returns the value of the key, and records a problem if it is empty
+func (p *parser) required(section, key string) string {
+
+	value := p.str(section, key, "")
+
+	if value == "" {
+		p.errorf(section, key, "empty or not exist")
+	}
+
+	return value
+}
+
+// list returns the comma separated values of the key, def if it is not set
+func (p *parser) list(section, key string, def []string) []string {
+
+	value, ok := p.value(section, key)
+
+	if !ok {
+		return def
+	}
+
+	list := make([]string, 0)
+
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+
+	return list
+}
+
+// boolean returns the value of the key, def if it is not set or empty
+func (p *parser) boolean(section, key string, def bool) bool {
+
+	switch strings.ToLower(p.str(section, key, "")) {
+	case "":
+		return def
+	case "1", "t", "true", "y", "yes", "on":
+		return true
+	case "0", "f", "false", "n", "no", "off":
+		return false
+	}
+
+	p.errorf(section, key, "invalid boolean: %s", p.str(section, key, ""))
+
+	return def
+}
+
+// duration returns the value of the key, def if it is not set or empty
+func (p *parser) duration(section, key, def string) time.Duration {
+
+	value, err := time.ParseDuration(p.str(section, key, def))
+	if err != nil {
+		p.errorf(section, key, "%s", err)
+	}
+
+	return value
+}
+
+// number returns the value of the key, def if it is not set or empty.
+// Values below min are invalid.
+func (p *parser) number(section, key string, def, min int) int {
+
+	str := p.str(section, key, "")
+
+	if str == "" {
+		return def
+	}
+
+	value, err := strconv.Atoi(str)
+	if err != nil {
+		p.errorf(section, key, "%s", err)
+		return def
+	} else if value < min {
+		p.errorf(section, key, "must be at least %d: %d", min, value)
+		return def
+	}
+
+	return value
+}
+
+// oneOf records a problem if value is not in options
+func (p *parser) oneOf(section, key, value string, options ...string) {
+
+	if !Contains(options, value) {
+		p.errorf(section, key, "invalid option: %s", value)
+	}
+}
+
+// absolute records a problem if path is not absolute, and reports whether it is fine
+func (p *parser) absolute(section, key, path string) bool {
+
+	if path != "" && path[0] != '/' {
+		p.errorf(section, key, "not an absolute path: %s", path)
+		return false
+	}
+
+	return true
+}
+
+// file records a problem if the path of the key does not exist, and reports whether it exists
+func (p *parser) file(section, key, path string) bool {
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		p.errorf(section, key, "file not exist: %s", path)
+		return false
+	}
+
+	return true
+}
+
+// url returns the value of the key if it is an absolute http or https URL
+func (p *parser) url(section, key, def string) string {
+
+	value := p.str(section, key, def)
+
+	if value == "" {
+		p.errorf(section, key, "empty or not exist")
+	} else if u, err := url.Parse(value); err != nil {
+		p.errorf(section, key, "%s", err)
+	} else if u.Scheme != "http" && u.Scheme != "https" {
+		p.errorf(section, key, "invalid scheme: %s", u.Scheme)
+	}
+
+	return value
+}
+
+// features returns the list of sections in the key, and records a problem for the invalid ones
+func (p *parser) features(section, key string, def, features []string) []string {
+
+	list := p.list(section, key, def)
+
+	for _, feature := range list {
+		if !Contains(features, feature) {
+			p.errorf(section, key, "invalid option: %s", feature)
+		}
+	}
+
+	return list
+}
+
+// Parse used to parse and check the configurations in the gven config file.
+// sections maps the valid values in report->structure to the section of the config file
+// they use, empty if none, see collector.Sections.
+// Only the enabled sections are checked, the missing keys get their default value.
+// Every problem is returned at once as Errors.
+func Parse(path string, sections map[string]string) (Config, error) {
 
 	conf := Config{}
 
-	// Load the config file
-	cfg, err := ini.Load(path)
+	features := make([]string, 0, len(sections))
+
+	for feature := range sections {
+		features = append(features, feature)
+	}
+
+	sort.Strings(features)
+
+	// Load the config file and its drop-in files
+	cfg, positions, err := load(path)
 	if err != nil {
-		return conf, fmt.Errorf("failed to parse %s: %s", path, err)
+		return conf, err
 	}
 
-	// Parse report->structure
-	conf.ReportStructure = cfg.Section("report").Key("structure").Strings(",")
+	p := &parser{cfg: cfg, positions: positions}
 
+	// Parse report->structure
+	conf.ReportStructure = p.features("report", "structure", nil, features)
 	if len(conf.ReportStructure) == 0 {
-		return conf, fmt.Errorf("failed to parse report->structure: empty or not exist")
+		p.errorf("report", "structure", "empty or not exist")
+	}
+
+	// Parse daemon->sections, by default the enabled ones from port and log.ssh
+	conf.DaemonSections = p.features("daemon", "sections",
+		intersect(conf.ReportStructure, "port", "log.ssh"), features)
+
+	// Parse metrics->sections, by default the enabled ones that are fast to collect
+	conf.MetricsSections = p.features("metrics", "sections",
+		intersect(conf.ReportStructure, "system", "port", "process", "log.ssh", "log.nginx"), features)
+
+	// collected reports whether the feature is collected by the report, the checks or the metrics
+	collected := func(feature string) bool {
+		return Contains(conf.ReportStructure, feature) || Contains(conf.DaemonSections, feature) ||
+			Contains(conf.MetricsSections, feature)
+	}
+
+	// enabled reports whether the section of the config file is used by a collected feature
+	enabled := func(section string) bool {
+
+		for feature, s := range sections {
+			if s == section && collected(feature) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	// Parse report->timeout
+	conf.ReportTimeout = p.duration("report", "timeout", "30m")
+
+	// Parse <section>->timeout
+	conf.SectionTimeout = make(map[string]time.Duration)
+
+	for _, feature := range features {
+
+		section := sections[feature]
+
+		if section != "" && collected(feature) && p.has(section, "timeout") {
+			conf.SectionTimeout[feature] = p.duration(section, "timeout", "")
+		}
 	}
 
-	for _, feature := range conf.ReportStructure {
-		if feature != "system" && feature != "ip" && feature != "port" &&
-			feature != "process" && feature != "clamav" && feature != "log.ssh" &&
-			feature != "log.nginx" {
+	// Parse report->json
+	conf.ReportJSON = p.list("report", "json", nil)
+	for _, v := range conf.ReportJSON {
+		p.oneOf("report", "json", v, "attachment", "file", "stdout")
+	}
 
-			return conf, fmt.Errorf("failed to parse reportStructure: invalid option: %s",
-				feature)
+	// Parse report->json_file
+	if Contains(conf.ReportJSON, "file") {
+		conf.ReportJSONFile = p.required("report", "json_file")
+		p.absolute("report", "json_file", conf.ReportJSONFile)
+	}
+
+	// Parse report->text_template and report->html_template
+	conf.TextTemplate = p.str("report", "text_template", "")
+	if conf.TextTemplate != "" && p.absolute("report", "text_template", conf.TextTemplate) {
+		if _, err := render.LoadTemplates(conf.TextTemplate, ""); err != nil {
+			p.errorf("report", "text_template", "%s", err)
+		}
+	}
+
+	conf.HTMLTemplate = p.str("report", "html_template", "")
+	if conf.HTMLTemplate != "" && p.absolute("report", "html_template", conf.HTMLTemplate) {
+		if _, err := render.LoadTemplates("", conf.HTMLTemplate); err != nil {
+			p.errorf("report", "html_template", "%s", err)
+		}
+	}
+
+	// Parse system->cpu_interval
+	if enabled("system") {
+		conf.CPUInterval = p.duration("system", "cpu_interval", "1s")
+		if conf.CPUInterval <= 0 || conf.CPUInterval > time.Minute {
+			p.errorf("system", "cpu_interval", "must be between 0 and 1m: %s", conf.CPUInterval)
 		}
 	}
 
 	// Parse port->protocol
-	conf.PortProtocol = cfg.Section("port").Key("protocol").Strings(",")
-	if len(conf.PortProtocol) != 0 {
+	if enabled("port") {
+		conf.PortProtocol = p.list("port", "protocol", []string{"tcp", "tcp6", "udp", "udp6"})
 		for _, v := range conf.PortProtocol {
-			if v != "tcp" && v != "tcp6" && v != "udp" && v != "udp6" {
-				return conf, fmt.Errorf("failed to parse port->protocol: invalid option: %s", v)
-			}
+			p.oneOf("port", "protocol", v, "tcp", "tcp6", "udp", "udp6")
 		}
 	}
 
 	// Parse process->sort
-	conf.ProcessSort = cfg.Section("process").Key("sort").String()
-	if conf.ProcessSort != "pid" && conf.ProcessSort != "name" &&
-		conf.ProcessSort != "user" && conf.ProcessSort != "cpu" &&
-		conf.ProcessSort != "memory" {
+	if enabled("process") {
+		conf.ProcessSort = p.str("process", "sort", "cpu")
+		p.oneOf("process", "sort", conf.ProcessSort, "pid", "name", "user", "cpu", "memory")
+	}
+
+	// Parse clamav->path
+	if enabled("clamav") {
+		conf.ClamAVPath = p.list("clamav", "path", nil)
+		for _, path := range conf.ClamAVPath {
+			p.absolute("clamav", "path", path)
+			// Path goes to a system() call, so sanitize is necessary
+			if err := sanitizeInput(path); err != nil {
+				p.errorf("clamav", "path", "%s", err)
+			}
+		}
+	}
+
+	// Parse log.ssh->follow
+	conf.SSHFollow = p.boolean("log.ssh", "follow", false)
 
-		return conf, fmt.Errorf("failed to parse process->sort: invalid option: %s",
-			conf.ProcessSort)
+	// Parse log.ssh->path, the follower needs it too
+	conf.SSHLogPath = p.str("log.ssh", "path", "/var/log/auth.log")
+	if enabled("log.ssh") || conf.SSHFollow {
+		p.file("log.ssh", "path", conf.SSHLogPath)
 	}
 
-	// Parse clamav->directory
-	conf.ClamAVPath = cfg.Section("clamav").Key("path").Strings(",")
-	for _, path := range conf.ClamAVPath {
-		if path[0] != '/' {
-			return conf, fmt.Errorf("failed to parse clamav->path: not an absolute path: %s",
-				path)
+	// Parse log.ssh->failed and log.ssh->multiple
+	conf.SSHParseFailed = p.boolean("log.ssh", "failed", true)
+	conf.SSHMultiple = p.boolean("log.ssh", "multiple", true)
+
+	// Parse log.ssh->allow_users
+	conf.SSHAllowUsers = p.list("log.ssh", "allow_users", nil)
+
+	// Parse log.ssh->allow_networks, single IPs are accepted too
+	for _, network := range p.list("log.ssh", "allow_networks", nil) {
+
+		if !strings.Contains(network, "/") {
+			if ip := net.ParseIP(network); ip != nil && ip.To4() != nil {
+				network += "/32"
+			} else {
+				network += "/128"
+			}
 		}
-		// Path goes to a system() call, so sanitize is necessary
-		if err := sanitizeInput(path); err != nil {
-			return conf, fmt.Errorf("failed to parse clamav->path: %s", err)
+
+		_, ipNet, err := net.ParseCIDR(network)
+		if err != nil {
+			p.errorf("log.ssh", "allow_networks", "%s", err)
+			continue
 		}
+
+		conf.SSHAllowNetworks = append(conf.SSHAllowNetworks, ipNet)
 	}
 
-	// Parse log.ssh->path
-	conf.SSHLogPath = cfg.Section("log.ssh").Key("path").String()
+	// Parse log.nginx->path
+	if enabled("log.nginx") {
+		conf.NginxLogPath = p.str("log.nginx", "path", "/var/log/nginx/access.log")
+		p.file("log.nginx", "path", conf.NginxLogPath)
+	}
 
-	if conf.SSHLogPath == "" {
-		return conf, fmt.Errorf("failed to parse log.ssh->path: empty or not exist")
+	// Parse report->notify
+	conf.Notifiers = p.list("report", "notify", []string{"smtp"})
+	for _, v := range conf.Notifiers {
+		p.oneOf("report", "notify", v, "smtp", "webhook", "slack", "telegram")
 	}
 
-	if _, err := os.Stat(conf.SSHLogPath); os.IsNotExist(err) {
-		return conf, fmt.Errorf("failed to parse log.ssh->path: file not exist: %s",
-			conf.SSHLogPath)
+	if Contains(conf.Notifiers, "smtp") {
+		p.smtp(&conf)
 	}
 
-	// Parse log.ssh->failed
-	conf.SSHParseFailed, err = cfg.Section("log.ssh").Key("failed").Bool()
-	if err != nil {
-		return conf, fmt.Errorf("failed to parse log.ssh->failed: %s", err)
+	if Contains(conf.Notifiers, "webhook") {
+		p.webhook(&conf)
 	}
 
-	// Parse log.ssh->multiple
-	conf.SSHMultiple, err = cfg.Section("log.ssh").Key("multiple").Bool()
-	if err != nil {
-		return conf, fmt.Errorf("failed to parse log.ssh->multiple: %s", err)
+	if Contains(conf.Notifiers, "slack") {
+		p.slack(&conf)
 	}
 
-	// parse log.nginx->path
-	conf.NginxLogPath = cfg.Section("log.nginx").Key("path").String()
-	if conf.NginxLogPath == "" {
-		return conf, fmt.Errorf("failed to parse log.nginx->path: empty or not exist")
+	if Contains(conf.Notifiers, "telegram") {
+		p.telegram(&conf)
 	}
 
-	if _, err := os.Stat(conf.NginxLogPath); os.IsNotExist(err) {
-		return conf, fmt.Errorf("failed to parse log.nginx->path: file not exist: %s",
-			conf.SSHLogPath)
+	// Parse the thresholds in the rules section, rules without value are disabled
+	conf.Thresholds = make(map[string]Threshold)
+
+	for _, rule := range thresholdRules {
+
+		values := p.list("rules", rule, nil)
+
+		if len(values) == 0 {
+			continue
+		}
+
+		if len(values) != 2 {
+			p.errorf("rules", rule, "expected warning,critical")
+			continue
+		}
+
+		limits := make([]float64, 2)
+		valid := true
+
+		for i, v := range values {
+			if limits[i], err = strconv.ParseFloat(v, 64); err != nil {
+				p.errorf("rules", rule, "invalid number: %s", v)
+				valid = false
+			}
+		}
+
+		if !valid {
+			continue
+		}
+
+		if limits[0] > limits[1] {
+			p.errorf("rules", rule, "warning is above critical")
+			continue
+		}
+
+		conf.Thresholds[rule] = Threshold{Warning: limits[0], Critical: limits[1]}
 	}
 
-	// Parse smtp->server
-	conf.SMTPServer = cfg.Section("smtp").Key("server").String()
-	if conf.SMTPServer == "" {
-		return conf, fmt.Errorf("failed to parse 'smtp->server': empty or not exist")
+	// Parse rules->clamav
+	if err := conf.ClamAVSeverity.UnmarshalText([]byte(p.str("rules", "clamav", "critical"))); err != nil {
+		p.errorf("rules", "clamav", "%s", err)
 	}
 
-	// Parse smtp->port
-	conf.SMTPPort, err = cfg.Section("smtp").Key("port").Int()
-	if err != nil {
-		return conf, fmt.Errorf("failed to parse 'smtp->port': %s", err)
-	} else if conf.SMTPPort > 65535 || conf.SMTPPort < 0 {
-		return conf, fmt.Errorf("invalid port number in smtp->port: %d", conf.SMTPPort)
+	// Parse daemon->report
+	conf.DaemonReport = p.str("daemon", "report", "0 4 * * *")
+	if _, err := schedule.Parse(conf.DaemonReport); err != nil {
+		p.errorf("daemon", "report", "%s", err)
 	}
 
-	// Parse smtp->user
-	conf.SMTPUser = cfg.Section("smtp").Key("user").String()
-	if conf.SMTPUser == "" {
-		return conf, fmt.Errorf("failed to parse 'smtp->user': empty or not exist")
+	// Parse daemon->check
+	conf.DaemonCheck = p.str("daemon", "check", "")
+	if conf.DaemonCheck != "" {
+		if _, err := schedule.Parse(conf.DaemonCheck); err != nil {
+			p.errorf("daemon", "check", "%s", err)
+		}
 	}
 
-	// Parse smtp->password
-	conf.SMTPPassword = cfg.Section("smtp").Key("password").String()
-	if conf.SMTPPassword == "" {
-		return conf, fmt.Errorf("failed to parse 'smtp->password': empty or not exist")
+	// Parse metrics->listen and metrics->top
+	conf.MetricsListen = p.str("metrics", "listen", "")
+	conf.MetricsTop = p.number("metrics", "top", 10, 1)
+
+	// Parse metrics->refresh
+	conf.MetricsRefresh = p.duration("metrics", "refresh", "15s")
+	if conf.MetricsRefresh < 0 {
+		p.errorf("metrics", "refresh", "must not be negative: %s", conf.MetricsRefresh)
 	}
 
-	// Parse smtp->recipient
-	conf.SMTPRecipient = cfg.Section("smtp").Key("recipient").String()
-	if conf.SMTPRecipient == "" {
-		return conf, fmt.Errorf("failed to parse 'smtp->recipient': empty or not exist")
+	// Parse state->path
+	conf.StatePath = p.str("state", "path", "/var/lib/vps-sentinel")
+	p.absolute("state", "path", conf.StatePath)
+
+	// Parse archive->enabled, archive->keep and archive->days
+	conf.ArchiveEnabled = p.boolean("archive", "enabled", true)
+	conf.ArchiveKeep = p.number("archive", "keep", 0, 0)
+	conf.ArchiveMaxAge = time.Duration(p.number("archive", "days", 30, 0)) * 24 * time.Hour
+
+	if len(p.errs) > 0 {
+		return conf, p.errs
 	}
 
 	return conf, nil
 }
+
+// intersect returns the values which are in list, in the order of values
+func intersect(list []string, values ...string) []string {
+
+	result := make([]string, 0)
+
+	for _, v := range values {
+		if Contains(list, v) {
+			result = append(result, v)
+		}
+	}
+
+	return result
+}
+
+// smtp parses the smtp section
+func (p *parser) smtp(conf *Config) {
+
+	// Parse smtp->transport, by default implicit TLS on port 465 and STARTTLS on the others
+	conf.SMTPPort = p.number("smtp", "port", 0, 1)
+
+	transport := "starttls"
+	if conf.SMTPPort == 465 {
+		transport = "tls"
+	}
+
+	conf.SMTPTransport = p.str("smtp", "transport", transport)
+	p.oneOf("smtp", "transport", conf.SMTPTransport, "starttls", "tls", "none", "sendmail")
+
+	if conf.SMTPTransport == "sendmail" {
+
+		// Parse smtp->sendmail
+		conf.SMTPSendmail = p.str("smtp", "sendmail", "/usr/sbin/sendmail")
+		p.absolute("smtp", "sendmail", conf.SMTPSendmail)
+		p.file("smtp", "sendmail", conf.SMTPSendmail)
+	} else {
+
+		// Parse smtp->server and smtp->port
+		conf.SMTPServer = p.required("smtp", "server")
+
+		// An invalid number is already reported by p.number
+		if p.str("smtp", "port", "") == "" {
+			p.errorf("smtp", "port", "empty or not exist")
+		} else if conf.SMTPPort > 65535 {
+			p.errorf("smtp", "port", "invalid port number: %d", conf.SMTPPort)
+		}
+
+		// Parse smtp->user and smtp->password, authentication is optional
+		conf.SMTPUser = p.str("smtp", "user", "")
+		conf.SMTPPassword = p.secret("smtp", "password")
+
+		if conf.SMTPUser != "" && conf.SMTPPassword == "" && !p.has("smtp", "password_file") {
+			p.errorf("smtp", "password", "empty or not exist, but smtp->user is set")
+		} else if conf.SMTPUser == "" && conf.SMTPPassword != "" {
+			p.errorf("smtp", "user", "empty or not exist, but smtp->password is set")
+		}
+
+		// Parse smtp->ca_file and smtp->server_name, used to verify the certificate of the server
+		conf.SMTPCAFile = p.str("smtp", "ca_file", "")
+		if conf.SMTPCAFile != "" {
+			p.file("smtp", "ca_file", conf.SMTPCAFile)
+		}
+
+		conf.SMTPServerName = p.str("smtp", "server_name", conf.SMTPServer)
+	}
+
+	// Parse smtp->from, by default the user
+	conf.SMTPFrom = p.str("smtp", "from", conf.SMTPUser)
+	if conf.SMTPFrom == "" {
+		p.errorf("smtp", "from", "empty or not exist, and smtp->user is not set")
+	}
+
+	// Parse smtp->recipient, smtp->cc and smtp->bcc, the recipients of the whole report
+	conf.SMTPRoutes = []Route{p.route("smtp", "recipient")}
+	conf.SMTPRoutes[0].Name = "default"
+
+	// Parse the smtp.route.<name> sections, recipients of some sections only
+	for _, section := range p.cfg.Sections() {
+
+		if !strings.HasPrefix(section.Name(), "smtp.route.") {
+			continue
+		}
+
+		route := p.route(section.Name(), "recipient")
+		route.Name = strings.TrimPrefix(section.Name(), "smtp.route.")
+		route.Sections = p.list(section.Name(), "sections", nil)
+
+		if len(route.Sections) == 0 {
+			p.errorf(section.Name(), "sections", "empty or not exist")
+		}
+
+		for _, feature := range route.Sections {
+			if !Contains(conf.ReportStructure, feature) {
+				p.errorf(section.Name(), "sections", "not in report->structure: %s", feature)
+			}
+		}
+
+		if len(route.To)+len(route.CC)+len(route.BCC) == 0 {
+			p.errorf(section.Name(), "recipient", "empty or not exist")
+		}
+
+		conf.SMTPRoutes = append(conf.SMTPRoutes, route)
+	}
+
+	// The default recipients can be left empty if every mail is routed
+	if len(conf.SMTPRoutes[0].To) == 0 && len(conf.SMTPRoutes) == 1 {
+		p.errorf("smtp", "recipient", "empty or not exist")
+	}
+
+	// Parse smtp->format
+	conf.SMTPFormat = p.str("smtp", "format", "both")
+	p.oneOf("smtp", "format", conf.SMTPFormat, "text", "html", "both")
+
+	// Parse smtp->encrypt_to, smtp->sign_key and smtp->sign_passphrase, the PGP/MIME settings
+	conf.SMTPEncryptTo = p.list("smtp", "encrypt_to", nil)
+	for _, path := range conf.SMTPEncryptTo {
+		if p.absolute("smtp", "encrypt_to", path) && p.file("smtp", "encrypt_to", path) {
+			if _, err := pgpmime.Load([]string{path}, "", ""); err != nil {
+				p.errorf("smtp", "encrypt_to", "%s", err)
+			}
+		}
+	}
+
+	conf.SMTPSignKey = p.str("smtp", "sign_key", "")
+	conf.SMTPPassphrase = p.secret("smtp", "sign_passphrase")
+
+	if conf.SMTPSignKey != "" && p.absolute("smtp", "sign_key", conf.SMTPSignKey) {
+		if err := private(conf.SMTPSignKey); err != nil {
+			p.errorf("smtp", "sign_key", "%s", err)
+		} else if _, err := pgpmime.Load(nil, conf.SMTPSignKey, conf.SMTPPassphrase); err != nil {
+			p.errorf("smtp", "sign_key", "%s", err)
+		}
+	}
+}
+
+// route parses the comma separated lists of addresses in key, cc and bcc of the section
+func (p *parser) route(section, key string) Route {
+
+	var route Route
+
+	addresses := func(key string) []string {
+
+		list := p.list(section, key, nil)
+
+		for _, address := range list {
+			if _, err := mail.ParseAddress(address); err != nil {
+				p.errorf(section, key, "invalid address: %s: %s", address, err)
+			}
+		}
+
+		return list
+	}
+
+	route.To = addresses(key)
+	route.CC = addresses("cc")
+	route.BCC = addresses("bcc")
+
+	return route
+}
+
+// webhook parses the webhook section
+func (p *parser) webhook(conf *Config) {
+
+	// Parse webhook->url
+	conf.WebhookURL = p.url("webhook", "url", "")
+
+	// Parse webhook->payload
+	conf.WebhookPayload = p.str("webhook", "payload", "report")
+	p.oneOf("webhook", "payload", conf.WebhookPayload, "report", "summary")
+
+	// Parse webhook->headers, format: Name: value, Name: value
+	conf.WebhookHeaders = make(map[string]string)
+
+	for _, header := range p.list("webhook", "headers", nil) {
+
+		parts := strings.SplitN(header, ":", 2)
+
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			p.errorf("webhook", "headers", "invalid header: %s", header)
+			continue
+		}
+
+		conf.WebhookHeaders[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+
+	// Parse webhook->secret
+	conf.WebhookSecret = p.secret("webhook", "secret")
+
+	// Parse webhook->retries
+	conf.WebhookRetries = p.number("webhook", "retries", 3, 0)
+
+	// Parse webhook->timeout
+	conf.WebhookTimeout = p.duration("webhook", "timeout", "30s")
+}
+
+// slack parses the slack section
+func (p *parser) slack(conf *Config) {
+
+	// Parse slack->url
+	conf.SlackURL = p.url("slack", "url", "")
+
+	// Parse slack->username and slack->channel
+	conf.SlackUsername = p.str("slack", "username", "")
+	conf.SlackChannel = p.str("slack", "channel", "")
+
+	// Parse slack->limit, the maximum number of items per list
+	conf.SlackLimit = p.number("slack", "limit", 5, 1)
+}
+
+// telegram parses the telegram section
+func (p *parser) telegram(conf *Config) {
+
+	// Parse telegram->api_url
+	conf.TelegramAPIURL = p.url("telegram", "api_url", "https://api.telegram.org")
+
+	// Parse telegram->token and telegram->chat_id
+	conf.TelegramToken = p.secret("telegram", "token")
+	conf.TelegramChatID = p.required("telegram", "chat_id")
+
+	if conf.TelegramToken == "" && !p.has("telegram", "token_file") {
+		p.errorf("telegram", "token", "empty or not exist")
+	}
+
+	// Parse telegram->limit, the maximum number of items per list
+	conf.TelegramLimit = p.number("telegram", "limit", 5, 1)
+}
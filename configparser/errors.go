@@ -0,0 +1,41 @@
+package configparser
+
+import (
+	"fmt"
+)
+
+// Error is a problem of a key in the configuration files
+type Error struct {
+	File    string // File of the key, or its section if the key is missing, empty if both are missing
+	Line    int
+	Section string
+	Key     string
+	Message string
+}
+
+func (e Error) Error() string {
+
+	if e.File != "" {
+		return fmt.Sprintf("%s:%d: failed to parse %s->%s: %s", e.File, e.Line, e.Section, e.Key, e.Message)
+	}
+
+	return fmt.Sprintf("failed to parse %s->%s: %s", e.Section, e.Key, e.Message)
+}
+
+// Errors is every problem found in the configuration files, in the order of the checks
+type Errors []Error
+
+func (e Errors) Error() string {
+
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+
+	msg := fmt.Sprintf("%d problems found:", len(e))
+
+	for _, err := range e {
+		msg += "\n- " + err.Error()
+	}
+
+	return msg
+}
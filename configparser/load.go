@@ -0,0 +1,234 @@
+package configparser
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/ini.v1"
+)
+
+// position is the place of a section or a key in the configuration files
+type position struct {
+	file string
+	line int
+}
+
+// secretKeys are masked in the effective configuration, by name or as section->key.
+// The URL of a Slack or Mattermost incoming webhook is the credential itself, other URLs may carry one too.
+var secretKeys = []string{"password", "secret", "token", "sign_passphrase", "slack->url", "webhook->url", "webhook->headers"}
+
+// mask returns the masked value of a secret key. The names of the headers are kept,
+// eg.: Authorization: ******** for Authorization: Bearer XXX
+func mask(key, value string) string {
+
+	if key != "headers" {
+		return "********"
+	}
+
+	headers := make([]string, 0)
+
+	for _, header := range strings.Split(value, ",") {
+		headers = append(headers, strings.TrimSpace(strings.SplitN(header, ":", 2)[0])+": ********")
+	}
+
+	return strings.Join(headers, ", ")
+}
+
+// DropInDir returns the directory of the drop-in files of the configuration file,
+// eg.: /etc/vps-sentinel.d for /etc/vps-sentinel.conf
+func DropInDir(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ".d"
+}
+
+// keyName returns the name of the key without the append operator, and whether it appends
+func keyName(name string) (string, bool) {
+
+	if strings.HasSuffix(name, "+") {
+		return strings.TrimSpace(strings.TrimSuffix(name, "+")), true
+	}
+
+	return name, false
+}
+
+// lineIndex returns the line number of the sections and keys in the file.
+// Keys are indexed as section->key, sections by their name.
+func lineIndex(path string) (map[string]int, error) {
+
+	lines := make(map[string]int)
+
+	f, err := os.Open(path)
+
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	section := ini.DefaultSection
+
+	for n := 1; scanner.Scan(); n++ {
+
+		line := strings.TrimSpace(scanner.Text())
+
+		switch {
+		case line == "" || line[0] == '#' || line[0] == ';':
+			continue
+		case line[0] == '[' && strings.HasSuffix(line, "]"):
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			if _, ok := lines[section]; !ok {
+				lines[section] = n
+			}
+		default:
+			if i := strings.IndexAny(line, "=:"); i > 0 {
+				name, _ := keyName(strings.TrimSpace(line[:i]))
+				lines[section+"->"+name] = n
+			}
+		}
+	}
+
+	return lines, scanner.Err()
+}
+
+// load reads the configuration file, then merges the *.conf files of its drop-in directory
+// in lexical order. A key replaces the previous value, key += value appends to the list.
+// The list must be set by an earlier file, the built-in default is not appended to.
+// Returns the merged file and the position of every section and key.
+func load(path string) (*ini.File, map[string]position, error) {
+
+	files := []string{path}
+
+	dropIns, err := filepath.Glob(filepath.Join(DropInDir(path), "*.conf"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list drop-in files: %s", err)
+	}
+
+	sort.Strings(dropIns)
+	files = append(files, dropIns...)
+
+	merged := ini.Empty()
+	positions := make(map[string]position)
+
+	for _, file := range files {
+
+		cfg, err := ini.Load(file)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse %s: %s", file, err)
+		}
+
+		lines, err := lineIndex(file)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read %s: %s", file, err)
+		}
+
+		for _, section := range cfg.Sections() {
+
+			target := merged.Section(section.Name())
+
+			if _, ok := positions[section.Name()]; !ok && lines[section.Name()] > 0 {
+				positions[section.Name()] = position{file, lines[section.Name()]}
+			}
+
+			for _, key := range section.Keys() {
+
+				name, appends := keyName(key.Name())
+				value := key.Value()
+
+				// Key and HasKey of ini would return the key of the parent section, eg.: smtp for smtp.route.x
+				prev, exists := target.KeysHash()[name]
+
+				if appends && !exists {
+					return nil, nil, Error{File: file, Line: lines[section.Name()+"->"+name], Section: section.Name(),
+						Key: name, Message: "+= needs the list set in an earlier file to append to"}
+				}
+
+				if appends && prev != "" && value != "" {
+					value = prev + "," + value
+				} else if appends && value == "" {
+					value = prev
+				}
+
+				if _, err := target.NewKey(name, value); err != nil {
+					return nil, nil, fmt.Errorf("failed to parse %s: %s", file, err)
+				}
+
+				positions[section.Name()+"->"+name] = position{file, lines[section.Name()+"->"+name]}
+			}
+		}
+	}
+
+	return merged, positions, nil
+}
+
+// Effective returns the configuration merged from the file and its drop-in files,
+// with the environment overrides applied. Every key shows where it comes from, the secrets are masked.
+func Effective(path string) (string, error) {
+
+	cfg, positions, err := load(path)
+	if err != nil {
+		return "", err
+	}
+
+	text := ""
+	overridden := make(map[string]bool)
+
+	for _, section := range cfg.Sections() {
+
+		if len(section.Keys()) == 0 {
+			continue
+		}
+
+		text += "[" + section.Name() + "]\n"
+
+		for _, key := range section.Keys() {
+
+			value := key.Value()
+			pos := positions[section.Name()+"->"+key.Name()]
+			from := fmt.Sprintf("%s:%d", pos.file, pos.line)
+
+			if env, ok := os.LookupEnv(envName(section.Name(), key.Name())); ok {
+				value = env
+				from = envName(section.Name(), key.Name())
+				overridden[from] = true
+			}
+
+			secret := Contains(secretKeys, key.Name()) || Contains(secretKeys, section.Name()+"->"+key.Name())
+
+			if secret && value != "" {
+				value = mask(key.Name(), value)
+			}
+
+			text += fmt.Sprintf("# %s\n%s = %s\n", from, key.Name(), value)
+		}
+
+		text += "\n"
+	}
+
+	// Overrides of the keys which are not in the files
+	env := make([]string, 0)
+
+	for _, v := range os.Environ() {
+
+		name := strings.SplitN(v, "=", 2)[0]
+
+		if strings.HasPrefix(name, EnvPrefix) && !overridden[name] {
+			env = append(env, name)
+		}
+	}
+
+	if len(env) > 0 {
+
+		sort.Strings(env)
+
+		text += "# Set in the environment:\n"
+
+		for _, name := range env {
+			text += "# - " + name + "\n"
+		}
+	}
+
+	return text, nil
+}
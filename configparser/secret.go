@@ -0,0 +1,113 @@
+package configparser
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// EnvPrefix is the prefix of the environment variables which override the keys
+const EnvPrefix = "VPS_SENTINEL_"
+
+// envReference matches a ${NAME} reference to an environment variable
+var envReference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// envName returns the environment variable which overrides the key,
+// eg.: VPS_SENTINEL_SMTP_PASSWORD for smtp->password, VPS_SENTINEL_LOG_SSH_PATH for log.ssh->path
+func envName(section, key string) string {
+
+	name := strings.ToUpper(section + "_" + key)
+
+	return EnvPrefix + strings.NewReplacer(".", "_", "-", "_").Replace(name)
+}
+
+// expand replaces the ${NAME} references in value with the environment variables.
+// Returns an error if a variable is not set.
+func expand(value string) (string, error) {
+
+	var err error
+
+	expanded := envReference.ReplaceAllStringFunc(value, func(ref string) string {
+
+		name := envReference.FindStringSubmatch(ref)[1]
+
+		v, ok := os.LookupEnv(name)
+
+		if !ok && err == nil {
+			err = fmt.Errorf("environment variable is not set: %s", name)
+		}
+
+		return v
+	})
+
+	return expanded, err
+}
+
+// private returns an error if the file is readable by group or others
+func private(path string) error {
+
+	info, err := os.Stat(path)
+
+	if err != nil {
+		return err
+	}
+
+	if info.Mode().Perm()&0044 != 0 {
+		return fmt.Errorf("%s is readable by group or others (%#o), run: chmod 600 %s",
+			path, info.Mode().Perm(), path)
+	}
+
+	return nil
+}
+
+// secret returns the value of the key, or the content of the file in <key>_file,
+// eg.: smtp->password or smtp->password_file.
+// The file holding the secret, including the configuration files, must not be readable
+// by group or others. A secret from the environment or a ${NAME} reference is always accepted.
+func (p *parser) secret(section, key string) string {
+
+	fileKey := key + "_file"
+
+	if path := p.str(section, fileKey, ""); path != "" {
+
+		// An empty key, eg.: password = of the sample configuration, is not a conflict
+		if p.str(section, key, "") != "" {
+			p.errorf(section, fileKey, "both %s and %s are set", key, fileKey)
+		}
+
+		if err := private(path); err != nil {
+			p.errorf(section, fileKey, "%s", err)
+			return ""
+		}
+
+		content, err := ioutil.ReadFile(path)
+
+		if err != nil {
+			p.errorf(section, fileKey, "failed to read %s: %s", path, err)
+			return ""
+		}
+
+		return strings.TrimRight(string(content), "\r\n")
+	}
+
+	value := p.str(section, key, "")
+
+	if _, ok := os.LookupEnv(envName(section, key)); ok || value == "" {
+		return value
+	}
+
+	// Only references to the environment, the secret is not in the file
+	raw := p.cfg.Section(section).Key(key).String()
+
+	if strings.TrimSpace(envReference.ReplaceAllString(raw, "")) == "" {
+		return value
+	}
+
+	if err := private(p.positions[section+"->"+key].file); err != nil {
+		p.errorf(section, key, "secret in a file which is not private: %s", err)
+	}
+
+	return value
+}
@@ -0,0 +1,63 @@
+package configparser
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testParser writes the configuration and the password file into a temporary directory,
+// and returns a parser of the configuration. ${DIR} in content is replaced with the directory.
+func testParser(t *testing.T, content string) *parser {
+
+	dir, err := ioutil.TempDir("", "vps-sentinel")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "password"), []byte("s3cret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "vps-sentinel.conf")
+
+	if err := ioutil.WriteFile(path, []byte(os.Expand(content, func(string) string { return dir })), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, positions, err := load(path)
+
+	if err != nil {
+		t.Fatalf("load() failed: %s", err)
+	}
+
+	return &parser{cfg: cfg, positions: positions}
+}
+
+func TestSecretFile(t *testing.T) {
+
+	for _, tc := range []struct {
+		name     string
+		content  string
+		conflict bool
+	}{
+		{"file only", "[smtp]\npassword_file = ${DIR}/password\n", false},
+		{"empty key and file", "[smtp]\npassword =\npassword_file = ${DIR}/password\n", false},
+		{"key and file", "[smtp]\npassword = inline\npassword_file = ${DIR}/password\n", true},
+	} {
+
+		p := testParser(t, tc.content)
+
+		if got := p.secret("smtp", "password"); got != "s3cret" {
+			t.Errorf("%s: secret() = %q, want %q", tc.name, got, "s3cret")
+		}
+
+		if conflict := len(p.errs) > 0; conflict != tc.conflict {
+			t.Errorf("%s: errors %v, want conflict: %t", tc.name, p.errs, tc.conflict)
+		}
+	}
+}
@@ -0,0 +1,407 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/g0rbe/vps-sentinel/collector"
+	"github.com/g0rbe/vps-sentinel/configparser"
+	"github.com/g0rbe/vps-sentinel/metrics"
+	"github.com/g0rbe/vps-sentinel/report"
+	"github.com/g0rbe/vps-sentinel/rules"
+	"github.com/g0rbe/vps-sentinel/schedule"
+	"github.com/g0rbe/vps-sentinel/state"
+	"github.com/g0rbe/vps-sentinel/sysinfo"
+)
+
+// job is a scheduled task of the daemon
+type job struct {
+	name     string
+	schedule *schedule.Schedule
+	run      func(ctx context.Context, conf configparser.Config, progress io.Writer) (report.Report, error)
+	next     time.Time
+}
+
+// daemon runs the jobs by their schedule
+type daemon struct {
+	opts options
+	conf configparser.Config
+	jobs []*job
+
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	running map[string]bool // Jobs currently running, to prevent overlapping runs
+
+	ctx          context.Context
+	stopFollower context.CancelFunc // Stops the SSH login follower, nil if not running
+	stopRetry    context.CancelFunc // Stops the retries of the mail spool, nil if not running
+
+	metrics       *metrics.Server    // Serves the sections of the jobs, nil if disabled
+	metricsListen string             // Address of the running metrics server
+	stopMetrics   context.CancelFunc // Stops the metrics server, nil if not running
+}
+
+// runCheck collects the sections in daemon->sections, and sends them if
+// anything changed since the previous check or a new issue found.
+// The checks have their own snapshots, so they do not hide the changes from the full report.
+func runCheck(ctx context.Context, conf configparser.Config, progress io.Writer) (report.Report, error) {
+
+	conf.ReportStructure = conf.DaemonSections
+
+	r := report.Report{
+		Kind:     report.KindCheck,
+		Host:     sysinfo.GetFqdn(),
+		Time:     time.Now(),
+		Sections: collector.Run(ctx, conf, progress)}
+
+	if ctx.Err() != nil {
+		return r, fmt.Errorf("check canceled: %s", ctx.Err())
+	}
+
+	store := state.New(filepath.Join(conf.StatePath, "checks"))
+
+	if err := store.Diff(&r); err != nil {
+		return r, fmt.Errorf("failed to compare with the previous check: %s", err)
+	}
+
+	rules.Evaluate(&r, conf)
+
+	// Issues of the previous check, to not send the same issue again and again
+	seen := make(map[string]bool)
+
+	for _, name := range conf.ReportStructure {
+
+		sections, _, _, err := store.Load(name)
+
+		if err != nil {
+			return r, err
+		}
+
+		for _, section := range sections {
+			for _, finding := range section.Findings {
+				seen[section.Title+finding.Message] = true
+			}
+		}
+	}
+
+	if err := store.Save(r); err != nil {
+		return r, fmt.Errorf("failed to save state: %s", err)
+	}
+
+	changed := false
+
+	for _, issue := range r.Issues() {
+		if !seen[issue.Section+issue.Message] {
+			changed = true
+		}
+	}
+
+	for _, section := range r.Sections {
+		if section.Changes != nil && !section.Changes.Empty() {
+			changed = true
+		}
+	}
+
+	if !changed {
+		fmt.Fprintf(progress, "Nothing new since the previous check\n")
+		return r, nil
+	}
+
+	return r, sendReport(ctx, conf, r, progress)
+}
+
+// schedule creates the jobs from the configuration and calculates their next run
+func (d *daemon) schedule(now time.Time) error {
+
+	reportSchedule, err := schedule.Parse(d.conf.DaemonReport)
+
+	if err != nil {
+		return err
+	}
+
+	d.jobs = []*job{{name: "report", schedule: reportSchedule, run: runReport}}
+
+	if d.conf.DaemonCheck != "" && len(d.conf.DaemonSections) > 0 {
+
+		checkSchedule, err := schedule.Parse(d.conf.DaemonCheck)
+
+		if err != nil {
+			return err
+		}
+
+		d.jobs = append(d.jobs, &job{name: "check", schedule: checkSchedule, run: runCheck})
+	}
+
+	for _, j := range d.jobs {
+
+		j.next = j.schedule.Next(now)
+
+		if j.next.IsZero() {
+			return fmt.Errorf("%s never runs: %s", j.name, j.schedule)
+		}
+
+		fmt.Printf("Next %s at %s (%s)\n", j.name, j.next.Format(time.RFC1123), j.schedule)
+	}
+
+	return nil
+}
+
+// start runs the job in the background, unless the previous run is still running
+func (d *daemon) start(j *job) {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.running[j.name] {
+		fmt.Fprintf(os.Stderr, "Skipping %s, the previous run is not finished\n", j.name)
+		return
+	}
+
+	d.running[j.name] = true
+	d.wg.Add(1)
+
+	go func(conf configparser.Config, m *metrics.Server) {
+
+		defer d.wg.Done()
+
+		fmt.Printf("Starting %s...\n", j.name)
+
+		r, err := j.run(d.ctx, conf, os.Stdout)
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to run %s: %s\n", j.name, err)
+		} else {
+			fmt.Printf("Finished %s\n", j.name)
+		}
+
+		// The sections of a canceled job failed with the context
+		if m != nil && d.ctx.Err() == nil {
+			m.Update(r.Sections)
+		}
+
+		d.mu.Lock()
+		d.running[j.name] = false
+		d.mu.Unlock()
+	}(d.conf, d.metrics)
+}
+
+// follow starts the SSH login follower if log.ssh->follow is set,
+// and stops the previous one. The follower is restarted on error.
+func (d *daemon) follow() {
+
+	if d.stopFollower != nil {
+		d.stopFollower()
+		d.stopFollower = nil
+	}
+
+	if !d.conf.SSHFollow {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(d.ctx)
+	d.stopFollower = cancel
+	d.wg.Add(1)
+
+	go func(conf configparser.Config) {
+
+		defer d.wg.Done()
+
+		for {
+			if err := followLogins(ctx, conf); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to follow SSH logins, restarting: %s\n", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(10 * time.Second):
+			}
+		}
+	}(d.conf)
+}
+
+// retry flushes the mail spool every minute if smtp is in report->notify,
+// and stops the previous loop. The spooled mails are sent when their backoff expires.
+func (d *daemon) retry() {
+
+	if d.stopRetry != nil {
+		d.stopRetry()
+		d.stopRetry = nil
+	}
+
+	if !configparser.Contains(d.conf.Notifiers, "smtp") {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(d.ctx)
+	d.stopRetry = cancel
+	d.wg.Add(1)
+
+	go func(conf configparser.Config) {
+
+		defer d.wg.Done()
+
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				flushSpool(ctx, conf, os.Stdout)
+			}
+		}
+	}(d.conf)
+}
+
+// serveMetrics starts the metrics server if metrics->listen is set,
+// and restarts it if the address changed.
+func (d *daemon) serveMetrics() {
+
+	if d.metrics != nil {
+		d.metrics.SetConfig(d.conf)
+	}
+
+	if d.conf.MetricsListen == d.metricsListen {
+		return
+	}
+
+	if d.stopMetrics != nil {
+		d.stopMetrics()
+		d.stopMetrics = nil
+	}
+
+	d.metricsListen = d.conf.MetricsListen
+
+	if d.conf.MetricsListen == "" {
+		d.metrics = nil
+		return
+	}
+
+	if d.metrics == nil {
+		d.metrics = metrics.NewServer(d.conf)
+	}
+
+	ctx, cancel := context.WithCancel(d.ctx)
+	d.stopMetrics = cancel
+	d.wg.Add(1)
+
+	go func(m *metrics.Server, addr string) {
+
+		defer d.wg.Done()
+
+		fmt.Printf("Serving metrics on %s/metrics\n", addr)
+
+		if err := m.ListenAndServe(ctx, addr); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to serve metrics: %s\n", err)
+		}
+	}(d.metrics, d.conf.MetricsListen)
+}
+
+// reload parses the configuration file again, keeps the old one on error
+func (d *daemon) reload() {
+
+	fmt.Printf("Reloading configuration file...\n")
+
+	conf, err := d.opts.loadConfig()
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to reload configuration file, keeping the old one: %s\n", err)
+		return
+	}
+
+	old := d.conf
+	d.conf = conf
+
+	if err := d.schedule(time.Now()); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to schedule jobs, keeping the old configuration: %s\n", err)
+		d.conf = old
+		d.schedule(time.Now())
+	}
+
+	d.follow()
+	d.retry()
+	d.serveMetrics()
+}
+
+// daemonCmd runs the full report and the checks by the schedules in the daemon section,
+// follows the SSH logins if log.ssh->follow is set, retries the spooled mails
+// and serves the metrics if metrics->listen is set.
+// SIGHUP reloads the configuration, SIGTERM and SIGINT stops the daemon
+// after the running jobs are cancelled.
+func daemonCmd(args []string) int {
+
+	d := &daemon{running: make(map[string]bool)}
+
+	fs := flag.NewFlagSet("daemon", flag.ExitOnError)
+	d.opts.register(fs)
+	fs.Parse(args)
+
+	var err error
+
+	if d.conf, err = d.opts.loadConfig(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to parse configuration file: %s\n", err)
+		return 1
+	}
+
+	if err := d.schedule(time.Now()); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to schedule jobs: %s\n", err)
+		return 1
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	d.ctx = ctx
+	d.follow()
+	d.retry()
+	d.serveMetrics()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP, syscall.SIGTERM, syscall.SIGINT)
+
+	for {
+		next := d.jobs[0].next
+
+		for _, j := range d.jobs {
+			if j.next.Before(next) {
+				next = j.next
+			}
+		}
+
+		timer := time.NewTimer(time.Until(next))
+
+		select {
+		case now := <-timer.C:
+			for _, j := range d.jobs {
+				if !j.next.After(now) {
+					d.start(j)
+					j.next = j.schedule.Next(now)
+				}
+			}
+		case sig := <-signals:
+			timer.Stop()
+
+			if sig == syscall.SIGHUP {
+				d.reload()
+				continue
+			}
+
+			fmt.Printf("Received %s, stopping...\n", sig)
+
+			cancel()
+			d.wg.Wait()
+
+			return 0
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/g0rbe/vps-sentinel/configparser"
+	"github.com/g0rbe/vps-sentinel/logparser"
+	"github.com/g0rbe/vps-sentinel/report"
+	"github.com/g0rbe/vps-sentinel/sysinfo"
+)
+
+// expectedLogin reports whether the login matches the allowlists in log.ssh.
+// Without allowlists, no login is expected.
+func expectedLogin(conf configparser.Config, login logparser.AcceptedLogin) bool {
+
+	if len(conf.SSHAllowUsers) == 0 && len(conf.SSHAllowNetworks) == 0 {
+		return false
+	}
+
+	if len(conf.SSHAllowUsers) > 0 && !configparser.Contains(conf.SSHAllowUsers, login.User) {
+		return false
+	}
+
+	if len(conf.SSHAllowNetworks) == 0 {
+		return true
+	}
+
+	ip := net.ParseIP(login.IP)
+
+	for _, network := range conf.SSHAllowNetworks {
+		if ip != nil && network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// loginAlert creates the alert of an accepted SSH login
+func loginAlert(login logparser.AcceptedLogin) report.Report {
+
+	table := report.NewTable(
+		report.Column{Name: "Time", Type: report.String},
+		report.Column{Name: "User", Type: report.String},
+		report.Column{Name: "IP", Type: report.String},
+		report.Column{Name: "Authentication type", Type: report.String})
+	table.Append(login.Time, login.User, login.IP, login.AuthType)
+
+	section := report.Section{
+		ID:        "log.ssh:login",
+		Collector: "log.ssh",
+		Title:     "SSH login",
+		Status:    report.StatusOK,
+		Table:     table,
+		Findings: []report.Finding{{Severity: report.Warning,
+			Message: fmt.Sprintf("SSH login of %s from %s (%s)", login.User, login.IP, login.AuthType)}}}
+
+	return report.Report{
+		Kind:     report.KindAlert,
+		Host:     sysinfo.GetFqdn(),
+		Time:     time.Now(),
+		Sections: []report.Section{section}}
+}
+
+// followLogins sends an alert for every unexpected login in log.ssh->path, until ctx is done
+func followLogins(ctx context.Context, conf configparser.Config) error {
+
+	fmt.Printf("Following SSH logins in %s...\n", conf.SSHLogPath)
+
+	return logparser.FollowAcceptedLogins(ctx, conf.SSHLogPath, func(login logparser.AcceptedLogin) {
+
+		if expectedLogin(conf, login) {
+			fmt.Printf("Expected SSH login of %s from %s\n", login.User, login.IP)
+			return
+		}
+
+		fmt.Printf("SSH login of %s from %s, sending alert...\n", login.User, login.IP)
+
+		if err := sendReport(ctx, conf, loginAlert(login), os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to send alert: %s\n", err)
+		}
+	})
+}
+
+// followCmd follows log.ssh->path and sends an alert for every accepted login
+func followCmd(args []string) int {
+
+	var opts options
+
+	fs := flag.NewFlagSet("follow", flag.ExitOnError)
+	opts.register(fs)
+	fs.Parse(args)
+
+	conf, err := opts.loadConfig()
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to parse configuration file: %s\n", err)
+		return 1
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+
+	go func() {
+		<-signals
+		cancel()
+	}()
+
+	if err := followLogins(ctx, conf); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to follow SSH logins: %s\n", err)
+		return 1
+	}
+
+	return 0
+}
@@ -3,6 +3,7 @@ module github.com/g0rbe/vps-sentinel
 go 1.14
 
 require (
+	github.com/ProtonMail/go-crypto v1.0.0
 	github.com/go-mail/mail v2.3.1+incompatible
 	github.com/go-openapi/strfmt v0.19.5 // indirect
 	github.com/jedib0t/go-pretty v4.3.0+incompatible
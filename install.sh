@@ -16,9 +16,11 @@ install() {
     
     cp ./bin/vps-sentinel.conf /etc/vps-sentinel.conf
     chmod 0600 /etc/vps-sentinel.conf
+    mkdir -p -m 0700 /etc/vps-sentinel.d
 
     cp ./bin/vps-sentinel.service /etc/systemd/system
     cp ./bin/vps-sentinel.timer /etc/systemd/system
+    cp ./bin/vps-sentinel-daemon.service /etc/systemd/system
 
     systemctl daemon-reload
     systemctl enable --now vps-sentinel.timer
@@ -32,10 +34,19 @@ remove() {
         exit 1
     fi
 
+    systemctl disable --now vps-sentinel.timer
+
+    # Installations before the daemon mode have no daemon unit
+    if [ -f /etc/systemd/system/vps-sentinel-daemon.service ]
+    then
+        systemctl disable --now vps-sentinel-daemon.service
+        rm /etc/systemd/system/vps-sentinel-daemon.service
+    fi
+
     rm /usr/bin/vps-sentinel
     rm /etc/vps-sentinel.conf
+    rm -rf /etc/vps-sentinel.d
 
-    systemctl disable --now vps-sentinel.timer
     rm /etc/systemd/system/vps-sentinel.*
     systemctl daemon-reload
 }
@@ -47,10 +58,18 @@ update() {
     chown root:root /usr/bin/vps-sentinel
     chmod 0500 /usr/bin/vps-sentinel
 
+    # Installations before the daemon mode have no daemon unit
+    cp ./bin/vps-sentinel-daemon.service /etc/systemd/system
+    systemctl daemon-reload
+
+    if systemctl is-active --quiet vps-sentinel-daemon.service
+    then
+        systemctl restart vps-sentinel-daemon.service
+    fi
 }
 
 build() {
-    go build main.go
+    go build -o main .
     mv ./main ./bin
 }
 
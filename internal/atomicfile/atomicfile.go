@@ -0,0 +1,47 @@
+// Package atomicfile replaces files atomically, a reader sees the old or the new content, never a part of it
+package atomicfile
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Write writes the content to a temporary file next to path, flushes it to the disk and
+// renames it to path. A crash leaves the previous file, and at most a stray path.*.tmp file.
+func Write(path string, content []byte, perm os.FileMode) error {
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file for %s: %s", path, err)
+	}
+
+	// Removes the temporary file on error, fails harmlessly after the rename
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %s", tmp.Name(), err)
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync %s: %s", tmp.Name(), err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %s", tmp.Name(), err)
+	}
+
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return fmt.Errorf("failed to chmod %s: %s", tmp.Name(), err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to rename %s: %s", tmp.Name(), err)
+	}
+
+	return nil
+}
@@ -0,0 +1,36 @@
+package ipinfo
+
+import (
+	"context"
+
+	"github.com/g0rbe/vps-sentinel/collector"
+	"github.com/g0rbe/vps-sentinel/configparser"
+	"github.com/g0rbe/vps-sentinel/report"
+)
+
+// ipCollector is the "ip" feature of the report
+type ipCollector struct{}
+
+func init() {
+	collector.Register(ipCollector{})
+}
+
+func (ipCollector) Name() string {
+	return "ip"
+}
+
+func (ipCollector) Title() string {
+	return "List of interfaces and its IP addresses"
+}
+
+func (ipCollector) Section() string {
+	return "ip"
+}
+
+func (ipCollector) Collect(ctx context.Context, conf configparser.Config) []report.Section {
+
+	section, err := GetIPInfo()
+
+	return []report.Section{
+		collector.NewSection("ip", "List of interfaces and its IP addresses", section, err)}
+}
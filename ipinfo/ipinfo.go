@@ -4,7 +4,7 @@ import (
 	"fmt"
 	"net"
 
-	"github.com/jedib0t/go-pretty/table"
+	"github.com/g0rbe/vps-sentinel/report"
 )
 
 // ifaceIP holds the iface name and it IP address
@@ -47,22 +47,24 @@ func getIPs() ([]ifaceIP, error) {
 }
 
 // GetIPInfo creates a report of interfaces and its associated IP addresses
-func GetIPInfo() (string, error) {
+func GetIPInfo() (report.Section, error) {
+
+	var section report.Section
 
 	ips, err := getIPs()
 
 	if err != nil {
-		return "", fmt.Errorf("failed to ip addresses: %s", err)
+		return section, fmt.Errorf("failed to ip addresses: %s", err)
 	}
 
-	t := table.NewWriter()
+	section.Table = report.NewTable(
+		report.Column{Name: "Interface", Type: report.String},
+		report.Column{Name: "Address", Type: report.String})
+	section.Table.Key = []string{"Interface", "Address"}
 
 	for _, ip := range ips {
-		t.AppendRow(table.Row{ip.Name, ip.IP})
+		section.Table.Append(ip.Name, ip.IP)
 	}
 
-	report := t.Render() + "\n\n"
-
-	return report, nil
-
+	return section, nil
 }
@@ -0,0 +1,77 @@
+package logparser
+
+import (
+	"context"
+
+	"github.com/g0rbe/vps-sentinel/collector"
+	"github.com/g0rbe/vps-sentinel/configparser"
+	"github.com/g0rbe/vps-sentinel/report"
+)
+
+// sshCollector is the "log.ssh" feature of the report
+type sshCollector struct{}
+
+// nginxCollector is the "log.nginx" feature of the report
+type nginxCollector struct{}
+
+func init() {
+	collector.Register(sshCollector{})
+	collector.Register(nginxCollector{})
+}
+
+func (sshCollector) Name() string {
+	return "log.ssh"
+}
+
+func (sshCollector) Title() string {
+	return "SSH logins"
+}
+
+func (sshCollector) Section() string {
+	return "log.ssh"
+}
+
+// Collect parses the accepted and, if log.ssh->failed is set, the failed logins
+func (sshCollector) Collect(ctx context.Context, conf configparser.Config) []report.Section {
+
+	accepted, err := GetAcceptedLogins(conf.SSHLogPath)
+
+	sections := []report.Section{
+		collector.NewSection("log.ssh:accepted", "Accepted SSH logins", accepted, err)}
+
+	if conf.SSHParseFailed {
+
+		failed, err := GetFailedLogins(conf.SSHLogPath, conf.SSHMultiple)
+
+		sections = append(sections,
+			collector.NewSection("log.ssh:failed", "Failed SSH logins", failed, err))
+	}
+
+	return sections
+}
+
+func (nginxCollector) Name() string {
+	return "log.nginx"
+}
+
+func (nginxCollector) Title() string {
+	return "Nginx errors"
+}
+
+func (nginxCollector) Section() string {
+	return "log.nginx"
+}
+
+// Collect parses the client and server errors from Nginx's access log
+func (nginxCollector) Collect(ctx context.Context, conf configparser.Config) []report.Section {
+
+	clientErrors, err := GetNginxClientErrors(conf.NginxLogPath)
+
+	sections := []report.Section{
+		collector.NewSection("log.nginx:client", "Nginx client errors", clientErrors, err)}
+
+	serverErrors, err := GetNginxServerErrors(conf.NginxLogPath)
+
+	return append(sections,
+		collector.NewSection("log.nginx:server", "Nginx server errors", serverErrors, err))
+}
@@ -0,0 +1,165 @@
+package logparser
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"syscall"
+	"time"
+)
+
+// followInterval is the time between the checks for new lines
+const followInterval = time.Second
+
+// follower reads the new lines of a file, and reopens it after logrotate
+type follower struct {
+	path   string
+	file   *os.File
+	reader *bufio.Reader
+	inode  uint64
+	offset int64
+	line   string // Partial line, waiting for the newline
+}
+
+// inodeOf returns the inode of the file info
+func inodeOf(info os.FileInfo) uint64 {
+
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return stat.Ino
+	}
+
+	return 0
+}
+
+// open opens the file and seeks to offset, use io.SeekEnd to skip the existing lines
+func (f *follower) open(whence int) error {
+
+	file, err := os.Open(f.path)
+
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %s", f.path, err)
+	}
+
+	info, err := file.Stat()
+
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat %s: %s", f.path, err)
+	}
+
+	offset, err := file.Seek(0, whence)
+
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to seek in %s: %s", f.path, err)
+	}
+
+	if f.file != nil {
+		f.file.Close()
+	}
+
+	f.file = file
+	f.reader = bufio.NewReader(file)
+	f.inode = inodeOf(info)
+	f.offset = offset
+	f.line = ""
+
+	return nil
+}
+
+// read calls fn for every complete new line
+func (f *follower) read(fn func(line string)) error {
+
+	for {
+		chunk, err := f.reader.ReadString('\n')
+
+		f.offset += int64(len(chunk))
+		f.line += chunk
+
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to read %s: %s", f.path, err)
+		}
+
+		fn(f.line[:len(f.line)-1])
+		f.line = ""
+	}
+}
+
+// checkRotation reopens the file if it is replaced (new inode) or truncated.
+// The rest of the old file is read before switching to the new one.
+func (f *follower) checkRotation(fn func(line string)) error {
+
+	info, err := os.Stat(f.path)
+
+	// The new file may not exist yet right after the rotation
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to stat %s: %s", f.path, err)
+	}
+
+	switch {
+	case inodeOf(info) != f.inode:
+		if err := f.read(fn); err != nil {
+			return err
+		}
+
+		return f.open(io.SeekStart)
+	case info.Size() < f.offset:
+		if _, err := f.file.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("failed to seek in %s: %s", f.path, err)
+		}
+
+		f.reader.Reset(f.file)
+		f.offset = 0
+		f.line = ""
+	}
+
+	return nil
+}
+
+// Follow calls fn for every line appended to the file after Follow is called, until ctx is done.
+// Follow survives logrotate: both the replaced (moved or removed) and the truncated file.
+func Follow(ctx context.Context, path string, fn func(line string)) error {
+
+	f := &follower{path: path}
+
+	if err := f.open(io.SeekEnd); err != nil {
+		return err
+	}
+
+	defer func() { f.file.Close() }()
+
+	ticker := time.NewTicker(followInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := f.read(fn); err != nil {
+			return err
+		}
+
+		if err := f.checkRotation(fn); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// FollowAcceptedLogins calls fn for every accepted login appended to the log, until ctx is done
+func FollowAcceptedLogins(ctx context.Context, path string, fn func(AcceptedLogin)) error {
+
+	return Follow(ctx, path, func(line string) {
+		if login, ok := ParseAcceptedLogin(line); ok {
+			fn(login)
+		}
+	})
+}
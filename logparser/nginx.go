@@ -7,7 +7,7 @@ import (
 	"strconv"
 	"strings"
 
-	"github.com/jedib0t/go-pretty/table"
+	"github.com/g0rbe/vps-sentinel/report"
 )
 
 // httpError holds informations about client/server errors (4XX and 5XX)
@@ -44,7 +44,7 @@ func parseClientErrors(path string) ([]httpError, error) {
 			return nil, fmt.Errorf("failed to convert %s to int: %s", codeStr, err)
 		}
 
-		if code < 400 || code > 500 {
+		if code < 400 || code >= 500 {
 			continue
 		}
 
@@ -90,7 +90,7 @@ func parseServerErrors(path string) ([]httpError, error) {
 
 		// I know that there is no 6XX errors, justwant to be sure that
 		// the code is in the 500 < code < 600 interval
-		if code < 500 || code > 600 {
+		if code < 500 || code >= 600 {
 			continue
 		}
 
@@ -109,50 +109,51 @@ func parseServerErrors(path string) ([]httpError, error) {
 	return httpErrorArray, nil
 }
 
-// GetNginxClientErrors creates a report from client errors
-func GetNginxClientErrors(path string) (string, error) {
+// httpErrorTable creates a table from the given errors
+func httpErrorTable(httpErrors []httpError) *report.Table {
 
-	clientErrors, err := parseClientErrors(path)
+	t := report.NewTable(
+		report.Column{Name: "Date", Type: report.String},
+		report.Column{Name: "IP", Type: report.String},
+		report.Column{Name: "Status", Type: report.Int},
+		report.Column{Name: "User Agent", Type: report.String},
+		report.Column{Name: "Request", Type: report.String})
 
-	if err != nil {
-		return "", fmt.Errorf("failed to parse client errors: %s", err)
+	for _, e := range httpErrors {
+		t.Append(e.Date, e.IP, e.Status, e.UserAgent, e.Request)
 	}
 
-	t := table.NewWriter()
+	return t
+}
 
-	t.AppendHeader(table.Row{"Date", "IP", "Status", "User Agent", "Request"})
+// GetNginxClientErrors creates a report from client errors
+func GetNginxClientErrors(path string) (report.Section, error) {
+
+	var section report.Section
 
-	for _, clientError := range clientErrors {
+	clientErrors, err := parseClientErrors(path)
 
-		t.AppendRow(table.Row{clientError.Date, clientError.IP, clientError.Status,
-			clientError.UserAgent, clientError.Request})
+	if err != nil {
+		return section, fmt.Errorf("failed to parse client errors: %s", err)
 	}
 
-	report := t.Render() + "\n\n"
+	section.Table = httpErrorTable(clientErrors)
 
-	return report, nil
+	return section, nil
 }
 
 // GetNginxServerErrors creates a report from sefrver errors
-func GetNginxServerErrors(path string) (string, error) {
-
-	clientErrors, err := parseServerErrors(path)
+func GetNginxServerErrors(path string) (report.Section, error) {
 
-	if err != nil {
-		return "", fmt.Errorf("failed to parse server errors: %s", err)
-	}
+	var section report.Section
 
-	t := table.NewWriter()
+	serverErrors, err := parseServerErrors(path)
 
-	t.AppendHeader(table.Row{"Date", "IP", "Status", "User Agent", "Request"})
-
-	for _, clientError := range clientErrors {
-
-		t.AppendRow(table.Row{clientError.Date, clientError.IP, clientError.Status,
-			clientError.UserAgent, clientError.Request})
+	if err != nil {
+		return section, fmt.Errorf("failed to parse server errors: %s", err)
 	}
 
-	report := t.Render() + "\n\n"
+	section.Table = httpErrorTable(serverErrors)
 
-	return report, nil
+	return section, nil
 }
@@ -4,13 +4,14 @@ import (
 	"bufio"
 	"fmt"
 	"os"
+	"sort"
 	"strings"
 
-	"github.com/jedib0t/go-pretty/table"
+	"github.com/g0rbe/vps-sentinel/report"
 )
 
-// acceptedSSHLogin hold inofrmations about accepted SSH logins
-type acceptedLogin struct {
+// AcceptedLogin hold inofrmations about accepted SSH logins
+type AcceptedLogin struct {
 	Time     string
 	User     string // The username which the user logged in
 	IP       string // The remote IP of the logged in user
@@ -23,10 +24,28 @@ type failedLogin struct {
 	Count int
 }
 
+// ParseAcceptedLogin parses an accepted login from a line of the log
+// Returns false if the line is not an accepted login.
+// Format: <month> <day> <time> <host> sshd[<pid>]: Accepted <type> for <user> from <ip> port <port> ...
+func ParseAcceptedLogin(line string) (AcceptedLogin, bool) {
+
+	fields := strings.Fields(line)
+
+	if len(fields) < 11 || !strings.Contains(fields[4], "sshd") || fields[5] != "Accepted" {
+		return AcceptedLogin{}, false
+	}
+
+	return AcceptedLogin{
+		Time:     strings.Join(fields[:3], " "),
+		User:     fields[8],
+		IP:       fields[10],
+		AuthType: fields[6]}, true
+}
+
 // parseAcceptedLogin searches the log file for accepted logins
-func parseAcceptedLogins(path string) ([]acceptedLogin, error) {
+func parseAcceptedLogins(path string) ([]AcceptedLogin, error) {
 
-	acceptedLoginArray := make([]acceptedLogin, 0)
+	acceptedLoginArray := make([]AcceptedLogin, 0)
 
 	logFile, err := os.Open(path)
 
@@ -40,17 +59,8 @@ func parseAcceptedLogins(path string) ([]acceptedLogin, error) {
 
 	for scanner.Scan() {
 
-		fields := strings.Fields(scanner.Text())
-
-		// Parse accepted logins
-		if strings.Contains(fields[4], "sshd") && fields[5] == "Accepted" {
-
-			timeStr := strings.Join(fields[:3], " ")
-
-			acceptedLoginArray = append(acceptedLoginArray,
-				acceptedLogin{User: fields[8], IP: fields[10], AuthType: fields[6],
-					Time: timeStr})
-
+		if login, ok := ParseAcceptedLogin(scanner.Text()); ok {
+			acceptedLoginArray = append(acceptedLoginArray, login)
 		}
 	}
 
@@ -99,9 +109,9 @@ func parseFailedLogins(path string) ([]failedLogin, error) {
 			newIP := true
 
 			// Check for existing IPs
-			for _, failed := range failedLoginArray {
-				if failed.IP == ip {
-					failed.Count++
+			for i := range failedLoginArray {
+				if failedLoginArray[i].IP == ip {
+					failedLoginArray[i].Count++
 					newIP = false
 					break
 				}
@@ -121,39 +131,47 @@ func parseFailedLogins(path string) ([]failedLogin, error) {
 }
 
 // GetAcceptedLogins generates a report of the accepted logins
-func GetAcceptedLogins(path string) (string, error) {
+func GetAcceptedLogins(path string) (report.Section, error) {
+
+	var section report.Section
 
 	logins, err := parseAcceptedLogins(path)
 
 	if err != nil {
-		return "", fmt.Errorf("failed to parse accepted logins: %s", err)
+		return section, fmt.Errorf("failed to parse accepted logins: %s", err)
 	}
 
-	t := table.NewWriter()
-
-	t.AppendHeader(table.Row{"Time", "User", "IP", "Authentication type"})
+	section.Table = report.NewTable(
+		report.Column{Name: "Time", Type: report.String},
+		report.Column{Name: "User", Type: report.String},
+		report.Column{Name: "IP", Type: report.String},
+		report.Column{Name: "Authentication type", Type: report.String})
+	section.Table.Key = []string{"User", "IP"}
 
 	for _, login := range logins {
-		t.AppendRow(table.Row{login.Time, login.User, login.IP, login.AuthType})
+		section.Table.Append(login.Time, login.User, login.IP, login.AuthType)
 	}
 
-	report := t.Render() + "\n\n"
-
-	return report, nil
+	return section, nil
 }
 
 // GetFailedLogins generates a report of the failed logins
-func GetFailedLogins(path string, multiple bool) (string, error) {
+// The list is sorted by the number of failed logins in descending order
+func GetFailedLogins(path string, multiple bool) (report.Section, error) {
+
+	var section report.Section
 
 	logins, err := parseFailedLogins(path)
 
 	if err != nil {
-		return "", fmt.Errorf("failed to parse failed logins: %s", err)
+		return section, fmt.Errorf("failed to parse failed logins: %s", err)
 	}
 
-	t := table.NewWriter()
+	sort.SliceStable(logins, func(i, j int) bool { return logins[i].Count > logins[j].Count })
 
-	t.AppendHeader(table.Row{"IP", "Count"})
+	section.Table = report.NewTable(
+		report.Column{Name: "IP", Type: report.String},
+		report.Column{Name: "Count", Type: report.Int})
 
 	for _, login := range logins {
 
@@ -161,14 +179,8 @@ func GetFailedLogins(path string, multiple bool) (string, error) {
 			continue
 		}
 
-		t.AppendRow(table.Row{login.IP, login.Count})
+		section.Table.Append(login.IP, login.Count)
 	}
 
-	sort := []table.SortBy{table.SortBy{Name: "Count", Mode: table.DscNumeric}}
-
-	t.SortBy(sort)
-
-	report := t.Render() + "\n\n"
-
-	return report, nil
+	return section, nil
 }
@@ -1,181 +1,145 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
+	"strings"
 
-	"github.com/g0rbe/vps-sentinel/logparser"
-
-	"github.com/g0rbe/vps-sentinel/clamav"
-	"github.com/g0rbe/vps-sentinel/ipinfo"
-
-	"github.com/g0rbe/vps-sentinel/process"
-
-	"github.com/go-mail/mail"
-
-	"github.com/g0rbe/vps-sentinel/sysinfo"
-
+	"github.com/g0rbe/vps-sentinel/collector"
 	"github.com/g0rbe/vps-sentinel/configparser"
-	"github.com/g0rbe/vps-sentinel/port"
-)
 
-func main() {
+	// Packages below register their collectors
+	_ "github.com/g0rbe/vps-sentinel/clamav"
+	_ "github.com/g0rbe/vps-sentinel/ipinfo"
+	_ "github.com/g0rbe/vps-sentinel/logparser"
+	_ "github.com/g0rbe/vps-sentinel/port"
+	_ "github.com/g0rbe/vps-sentinel/process"
+	_ "github.com/g0rbe/vps-sentinel/sysinfo"
+)
 
-	fmt.Printf("Parsing configuration file...\n")
+// defaultConfig is the path of the configuration file, if --config is not given
+const defaultConfig = "/etc/vps-sentinel.conf"
 
-	conf, err := configparser.Parse("/etc/vps-sentinel.conf")
+// command is a subcommand of vps-sentinel
+type command struct {
+	name  string
+	usage string
+	run   func(args []string) int // Returns the exit status
+}
 
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to parse configuration file: %s\n", err)
-		os.Exit(1)
-	}
+var commands = []command{
+	{"run", "collect the report and send it (default)", runCmd},
+	{"print", "collect the report and print it to the standard output", printCmd},
+	{"check-config", "check the configuration file and report every problem", checkConfigCmd},
+	{"send-test", "send a test message with every notifier", sendTestCmd},
+	{"follow", "send an alert immediately for every accepted SSH login", followCmd},
+	{"daemon", "run the report and the checks by the schedules in the daemon section", daemonCmd},
+	{"list", "list the archived reports", listCmd},
+	{"show", "print an archived report, eg.: show 2020-05-01", showCmd},
+	{"diff", "print the changes between two archived reports, eg.: diff 2020-05-01 2020-05-02", diffCmd},
+	{"queue", "list, flush or drop the mails which failed to send, eg.: queue list", queueCmd},
+}
 
-	var report string
+// usage prints the list of subcommands
+func usage() {
 
-	for _, feature := range conf.ReportStructure {
+	fmt.Fprintf(os.Stderr, "Usage: vps-sentinel [command] [flags]\n\nCommands:\n")
 
-		switch feature {
-		case "system":
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-14s %s\n", c.name, c.usage)
+	}
 
-			report += "############## " + "System informations" + " ##############\n\n"
+	fmt.Fprintf(os.Stderr, "\nRun 'vps-sentinel <command> -h' to list the flags of a command.\n")
+}
 
-			fmt.Printf("Getting system informations...\n")
+// options holds the flags common to every subcommand
+type options struct {
+	config string
+	only   string
+	skip   string
+}
 
-			if sInfo, err := sysinfo.GetSysInfo(); err != nil {
-				fmt.Fprintf(os.Stderr, "Failed to get system informations: %s\n", err)
-				report += fmt.Sprintf("Failed to get system informations: %s\n", err)
-			} else {
-				report += sInfo
-			}
-		case "ip":
+// register adds the common flags to the flag set
+func (o *options) register(fs *flag.FlagSet) {
 
-			report += "###### " + "List of interfaces and its IP addresses" + " ######\n\n"
+	fs.StringVar(&o.config, "config", defaultConfig, "path of the configuration file")
+	fs.StringVar(&o.only, "only", "", "comma separated list of sections to collect, others are skipped")
+	fs.StringVar(&o.skip, "skip", "", "comma separated list of sections to skip")
+}
 
-			fmt.Printf("Getting ip informations...\n")
+// splitList splits a comma separated list and drops the empty elements
+func splitList(list string) []string {
 
-			if netInfo, err := ipinfo.GetIPInfo(); err != nil {
-				fmt.Fprintf(os.Stderr, "Failed to get network informations: %s\n", err)
-				report += fmt.Sprintf("Failed to get network informations: %s\n", err)
-			} else {
-				report += netInfo
-			}
-		case "port":
+	result := make([]string, 0)
 
-			// Iterate over the given protocols to get a report of listening ports
-			for _, protocol := range conf.PortProtocol {
+	for _, v := range strings.Split(list, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			result = append(result, v)
+		}
+	}
 
-				report += "##### " + "Open ports (" + protocol + ")" + " #####\n\n"
+	return result
+}
 
-				fmt.Printf("Getting open ports of %s...\n", protocol)
+// loadConfig parses the configuration file and applies --only and --skip to report->structure
+func (o *options) loadConfig() (configparser.Config, error) {
 
-				if ports, err := port.GetListeningPorts(protocol); err != nil {
-					fmt.Fprintf(os.Stderr, "Failed to parse %s: %s\n", protocol, err)
-					report += fmt.Sprintf("Failed to parse %s: %s\n", protocol, err)
-				} else {
-					report += ports
-				}
-			}
-		case "process":
+	conf, err := configparser.Parse(o.config, collector.Sections())
 
-			report += "################################## " +
-				"List of processes" + " ##################################\n\n"
+	if err != nil {
+		return conf, err
+	}
 
-			fmt.Printf("Generating a list of processes...\n")
+	only := splitList(o.only)
+	skip := splitList(o.skip)
 
-			if procList, err := process.GetReport(conf.ProcessSort); err != nil {
-				fmt.Fprintf(os.Stderr, "Failed to list processes: %s\n", err)
-				report += fmt.Sprintf("Failed to list processes: %s\n", err)
-			} else {
-				report += procList
-			}
-		case "clamav":
+	for _, name := range append(only, skip...) {
+		if _, ok := collector.Get(name); !ok {
+			return conf, fmt.Errorf("invalid section: %s", name)
+		}
+	}
 
-			// Scan with ClamAV in the given paths
-			for _, path := range conf.ClamAVPath {
+	structure := make([]string, 0, len(conf.ReportStructure))
 
-				report += "###################### " +
-					"ClamAV scan in " + path + " #######################\n\n"
+	for _, name := range conf.ReportStructure {
 
-				fmt.Printf("Running ClamAV in %s...\n", path)
+		if len(only) > 0 && !configparser.Contains(only, name) {
+			continue
+		}
 
-				if out, err := clamav.RunClamAV(path); err != nil {
-					fmt.Fprintf(os.Stderr, "Failed to run clamav on %s: %s\n", path, err)
-					report += fmt.Sprintf("Failed to run clamav on %s: %s\n", path, err)
-				} else {
-					report += out
-				}
-			}
-		case "log.ssh":
+		if configparser.Contains(skip, name) {
+			continue
+		}
 
-			report += "##################### " +
-				"Accepted SSH logins" + " ######################\n\n"
+		structure = append(structure, name)
+	}
 
-			fmt.Printf("Finding accepted SSH logins...\n")
+	conf.ReportStructure = structure
 
-			if out, err := logparser.GetAcceptedLogins(conf.SSHLogPath); err != nil {
-				fmt.Fprintf(os.Stderr, "Failed to get accepted SSH logins: %s", err)
-				report += fmt.Sprintf("Failed to get accepted SSH logins: %s", err)
-			} else {
-				report += out
-			}
+	return conf, nil
+}
 
-			if conf.SSHParseFailed {
-				report += "######## " +
-					"Failed SSH logins" + " ########\n\n"
+func main() {
 
-				fmt.Printf("Finding failed SSH logins...\n")
+	args := os.Args[1:]
 
-				if out, err := logparser.GetFailedLogins(conf.SSHLogPath, conf.SSHMultiple); err != nil {
-					fmt.Fprintf(os.Stderr, "Failed to get failed SSH logins: %s", err)
-					report += fmt.Sprintf("Failed to get failed SSH logins: %s", err)
-				} else {
-					report += out
-				}
-			}
+	// Without a command, keep the original behaviour: collect and send
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") && args[0] != "-h" && args[0] != "--help" {
+		os.Exit(runCmd(args))
+	}
 
-		case "log.nginx":
-
-			report += "##################### " +
-				"Nginx client errors " + " ######################\n\n"
-
-			fmt.Printf("Finding client errors in Nginx's log...\n")
-
-			if out, err := logparser.GetNginxClientErrors(conf.NginxLogPath); err != nil {
-				fmt.Fprintf(os.Stderr, "Failed to get Nginx client errors: %s\n", err)
-				report += fmt.Sprintf("Failed to get Nginx client errors: %s\n", err)
-			} else {
-				report += out
-			}
-
-			report += "##################### " +
-				"Nginx server errors " + " ######################\n\n"
-
-			fmt.Printf("Finding server errors in Nginx's log...\n")
-
-			if out, err := logparser.GetNginxServerErrors(conf.NginxLogPath); err != nil {
-				fmt.Fprintf(os.Stderr, "Failed to get Nginx server errors: %s\n", err)
-				report += fmt.Sprintf("Failed to get Nginx server errors: %s\n", err)
-			} else {
-				report += out
-			}
+	for _, c := range commands {
+		if c.name == args[0] {
+			os.Exit(c.run(args[1:]))
 		}
 	}
 
-	fmt.Printf("Sending report...\n")
-
-	subj := fmt.Sprintf("[%s] Daily report from vps-sentinel", sysinfo.GetFqdn())
-
-	m := mail.NewMessage()
-	m.SetHeader("From", conf.SMTPUser)
-	m.SetHeader("To", conf.SMTPRecipient)
-	m.SetHeader("Subject", subj)
-	m.SetBody("text/plain", report)
-
-	d := mail.NewDialer(conf.SMTPServer, conf.SMTPPort, conf.SMTPUser, conf.SMTPPassword)
-	d.StartTLSPolicy = mail.MandatoryStartTLS
-
-	if err := d.DialAndSend(m); err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to send mail: %s\n", err)
-		os.Exit(1)
+	if args[0] != "help" && args[0] != "-h" && args[0] != "--help" {
+		fmt.Fprintf(os.Stderr, "Invalid command: %s\n\n", args[0])
+		usage()
+		os.Exit(2)
 	}
+
+	usage()
 }
@@ -0,0 +1,308 @@
+// Package metrics exposes the content of the report in the Prometheus text format
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/g0rbe/vps-sentinel/report"
+)
+
+// ContentType is the content type of the Prometheus text format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// label is a name-value pair of a sample
+type label struct {
+	name  string
+	value string
+}
+
+// sample is one value of a metric
+type sample struct {
+	labels []label
+	value  float64
+}
+
+// family is a metric with its samples
+type family struct {
+	name    string
+	help    string
+	typ     string // gauge or counter
+	samples []sample
+}
+
+// families collects the samples by metric, in the order of their first use
+type families struct {
+	order  []string
+	byName map[string]*family
+}
+
+// add adds a sample to the named metric
+func (f *families) add(name, typ, help string, value float64, labels ...label) {
+
+	if f.byName == nil {
+		f.byName = make(map[string]*family)
+	}
+
+	fam, ok := f.byName[name]
+
+	if !ok {
+		fam = &family{name: name, help: help, typ: typ}
+		f.byName[name] = fam
+		f.order = append(f.order, name)
+	}
+
+	fam.samples = append(fam.samples, sample{labels: labels, value: value})
+}
+
+// escape escapes the label value by the text format
+func escape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+// write writes the metrics in the text format
+func (f *families) write(w io.Writer) error {
+
+	for _, name := range f.order {
+
+		fam := f.byName[name]
+
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, fam.help, name, fam.typ); err != nil {
+			return err
+		}
+
+		for _, s := range fam.samples {
+
+			labels := make([]string, len(s.labels))
+
+			for i, l := range s.labels {
+				labels[i] = fmt.Sprintf("%s=\"%s\"", l.name, escape(l.value))
+			}
+
+			line := name
+
+			if len(labels) > 0 {
+				line += "{" + strings.Join(labels, ",") + "}"
+			}
+
+			if _, err := fmt.Fprintf(w, "%s %s\n", line, strconv.FormatFloat(s.value, 'g', -1, 64)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// number converts the numeric value of a field or a cell to float64
+func number(v interface{}) (float64, bool) {
+
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	}
+
+	return 0, false
+}
+
+// systemFields maps the fields of the system section to metrics
+var systemFields = map[string]struct {
+	name string
+	help string
+}{
+	"load1":            {"vps_sentinel_load1", "Average system load (1 min)"},
+	"load5":            {"vps_sentinel_load5", "Average system load (5 min)"},
+	"load15":           {"vps_sentinel_load15", "Average system load (15 min)"},
+	"online_cpus":      {"vps_sentinel_online_cpus", "Number of online CPUs"},
+	"mem_available":    {"vps_sentinel_memory_available_bytes", "Memory available without swapping in bytes"},
+	"mem_free":         {"vps_sentinel_memory_free_bytes", "Unused memory in bytes"},
+	"mem_total":        {"vps_sentinel_memory_total_bytes", "Total memory in bytes"},
+	"mem_buffers":      {"vps_sentinel_memory_buffers_bytes", "Memory used by buffers in bytes"},
+	"mem_cached":       {"vps_sentinel_memory_cached_bytes", "Memory used by the page cache in bytes"},
+	"mem_shmem":        {"vps_sentinel_memory_shmem_bytes", "Shared memory and tmpfs in bytes"},
+	"mem_sreclaimable": {"vps_sentinel_memory_sreclaimable_bytes", "Reclaimable slab memory in bytes"},
+	"mem_dirty":        {"vps_sentinel_memory_dirty_bytes", "Memory waiting to be written to the disk in bytes"},
+	"mem_writeback":    {"vps_sentinel_memory_writeback_bytes", "Memory being written to the disk in bytes"},
+	"mem_committed":    {"vps_sentinel_memory_committed_bytes", "Memory allocated by the processes (Committed_AS) in bytes"},
+	"mem_commit_limit": {"vps_sentinel_memory_commit_limit_bytes", "Commit limit in bytes"},
+	"swap_free":        {"vps_sentinel_swap_free_bytes", "Free swap in bytes"},
+	"swap_total":       {"vps_sentinel_swap_total_bytes", "Total swap in bytes"},
+	"uptime":           {"vps_sentinel_uptime_seconds", "Uptime in seconds"},
+}
+
+// system adds the fields of the system section
+func (f *families) system(s report.Section) {
+
+	for _, field := range s.Fields {
+
+		m, ok := systemFields[field.Key]
+		value, isNumber := number(field.Value)
+
+		if ok && isNumber {
+			f.add(m.name, "gauge", m.help, value)
+		}
+	}
+}
+
+// ports adds the number of listening ports of a port section
+func (f *families) ports(s report.Section) {
+
+	if s.Table == nil {
+		return
+	}
+
+	f.add("vps_sentinel_listening_ports", "gauge", "Number of listening ports",
+		float64(len(s.Table.Rows)), label{"protocol", strings.TrimPrefix(s.ID, "port:")})
+}
+
+// processes adds the CPU and memory usage of the first top processes, in the order of process->sort
+func (f *families) processes(s report.Section, top int) {
+
+	if s.Table == nil {
+		return
+	}
+
+	pid, name, user := s.Table.Column("Pid"), s.Table.Column("Name"), s.Table.Column("User")
+	cpu, mem := s.Table.Column("CPU"), s.Table.Column("Memory (MiB)")
+
+	if pid == -1 || name == -1 || user == -1 || cpu == -1 || mem == -1 {
+		return
+	}
+
+	for i, row := range s.Table.Rows {
+
+		if i == top {
+			break
+		}
+
+		labels := []label{
+			{"pid", fmt.Sprint(row[pid])},
+			{"name", fmt.Sprint(row[name])},
+			{"user", fmt.Sprint(row[user])}}
+
+		if value, ok := number(row[cpu]); ok {
+			f.add("vps_sentinel_process_cpu_percent", "gauge", "CPU usage of the top processes in percent",
+				value, labels...)
+		}
+
+		if value, ok := number(row[mem]); ok {
+			f.add("vps_sentinel_process_memory_bytes", "gauge", "Memory usage of the top processes in bytes",
+				value*1024*1024, labels...)
+		}
+	}
+}
+
+// sshFailed adds the failed SSH logins per IP.
+// The counters are reset when the log is rotated.
+func (f *families) sshFailed(s report.Section) {
+
+	if s.Table == nil {
+		return
+	}
+
+	ip, count := s.Table.Column("IP"), s.Table.Column("Count")
+
+	if ip == -1 || count == -1 {
+		return
+	}
+
+	for _, row := range s.Table.Rows {
+		if value, ok := number(row[count]); ok {
+			f.add("vps_sentinel_ssh_failed_logins_total", "counter", "Failed SSH logins in the log per IP",
+				value, label{"ip", fmt.Sprint(row[ip])})
+		}
+	}
+}
+
+// nginxErrors adds the number of client or server errors in the Nginx log per status code.
+// The counters are reset when the log is rotated.
+func (f *families) nginxErrors(s report.Section) {
+
+	if s.Table == nil {
+		return
+	}
+
+	status := s.Table.Column("Status")
+
+	if status == -1 {
+		return
+	}
+
+	counts := make(map[string]int)
+
+	for _, row := range s.Table.Rows {
+		counts[fmt.Sprint(row[status])]++
+	}
+
+	codes := make([]string, 0, len(counts))
+
+	for code := range counts {
+		codes = append(codes, code)
+	}
+
+	sort.Strings(codes)
+
+	for _, code := range codes {
+		f.add("vps_sentinel_nginx_errors_total", "counter", "Client and server errors in the Nginx log per status code",
+			float64(counts[code]), label{"status", code})
+	}
+}
+
+// clamav adds the number of infected files of a ClamAV scan
+func (f *families) clamav(s report.Section) {
+
+	if s.Table == nil {
+		return
+	}
+
+	f.add("vps_sentinel_clamav_infected_files", "gauge", "Infected files found by the last ClamAV scan",
+		float64(len(s.Table.Rows)), label{"path", strings.TrimPrefix(s.ID, "clamav:")})
+}
+
+// Write writes the metrics of the sections to w in the Prometheus text format.
+// Only the first top processes of the process section are written.
+func Write(w io.Writer, sections []report.Section, top int) error {
+
+	var f families
+
+	for _, s := range sections {
+
+		up := 0.0
+
+		if s.Status == report.StatusOK {
+			up = 1
+		}
+
+		f.add("vps_sentinel_section_up", "gauge", "Whether the last collection of the section succeeded",
+			up, label{"section", s.ID})
+	}
+
+	for _, s := range sections {
+
+		if s.Status != report.StatusOK {
+			continue
+		}
+
+		switch {
+		case s.ID == "system":
+			f.system(s)
+		case s.Collector == "port":
+			f.ports(s)
+		case s.ID == "process":
+			f.processes(s, top)
+		case s.ID == "log.ssh:failed":
+			f.sshFailed(s)
+		case s.ID == "log.nginx:client" || s.ID == "log.nginx:server":
+			f.nginxErrors(s)
+		case s.Collector == "clamav":
+			f.clamav(s)
+		}
+	}
+
+	return f.write(w)
+}
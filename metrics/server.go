@@ -0,0 +1,142 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/g0rbe/vps-sentinel/collector"
+	"github.com/g0rbe/vps-sentinel/configparser"
+	"github.com/g0rbe/vps-sentinel/report"
+)
+
+// Server serves the metrics on /metrics.
+// The sections in metrics->sections are collected by a scrape if they are older than metrics->refresh,
+// the others are kept from the last Update.
+type Server struct {
+	mu       sync.Mutex
+	conf     configparser.Config
+	sections map[string][]report.Section // Last sections by collector
+
+	// collecting serializes the collections of the scrapes, so parallel scrapes share one
+	collecting sync.Mutex
+	collected  time.Time
+}
+
+// NewServer returns a server without sections
+func NewServer(conf configparser.Config) *Server {
+	return &Server{conf: conf, sections: make(map[string][]report.Section)}
+}
+
+// SetConfig replaces the configuration, eg. after a reload
+func (s *Server) SetConfig(conf configparser.Config) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.conf = conf
+}
+
+// Update replaces the sections of the collectors in sections
+func (s *Server) Update(sections []report.Section) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	updated := make(map[string][]report.Section)
+
+	for _, section := range sections {
+		updated[section.Collector] = append(updated[section.Collector], section)
+	}
+
+	for name, sections := range updated {
+		s.sections[name] = sections
+	}
+}
+
+// refresh collects the sections in metrics->sections, unless the last collection is
+// younger than metrics->refresh. The system section takes system->cpu_interval to collect.
+func (s *Server) refresh(ctx context.Context, conf configparser.Config) {
+
+	s.collecting.Lock()
+	defer s.collecting.Unlock()
+
+	if time.Since(s.collected) < conf.MetricsRefresh {
+		return
+	}
+
+	conf.ReportStructure = conf.MetricsSections
+
+	s.Update(collector.Run(ctx, conf, ioutil.Discard))
+
+	// A canceled scrape leaves timed out sections, the next scrape collects again
+	if ctx.Err() == nil {
+		s.collected = time.Now()
+	}
+}
+
+// ServeHTTP collects the sections in metrics->sections if needed, and writes the metrics
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	s.mu.Lock()
+	conf := s.conf
+	s.mu.Unlock()
+
+	s.refresh(r.Context(), conf)
+
+	// Sections of the report, then the ones only scraped or checked by the daemon
+	sections := make([]report.Section, 0)
+	seen := make(map[string]bool)
+
+	s.mu.Lock()
+	for _, list := range [][]string{conf.ReportStructure, conf.MetricsSections, conf.DaemonSections} {
+		for _, name := range list {
+			if !seen[name] {
+				seen[name] = true
+				sections = append(sections, s.sections[name]...)
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	var buf bytes.Buffer
+
+	if err := Write(&buf, sections, conf.MetricsTop); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", ContentType)
+	w.Write(buf.Bytes())
+}
+
+// ListenAndServe serves the metrics on addr until ctx is done
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", s)
+
+	srv := &http.Server{Addr: addr, Handler: mux}
+
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to stop metrics server: %s\n", err)
+		}
+	}()
+
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return fmt.Errorf("failed to serve metrics on %s: %s", addr, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,39 @@
+// Package notify delivers the report to the configured destinations
+package notify
+
+import (
+	"context"
+
+	"github.com/g0rbe/vps-sentinel/configparser"
+	"github.com/g0rbe/vps-sentinel/report"
+)
+
+// Notifier delivers the report to one destination
+type Notifier interface {
+	// Name returns the name used in report->notify
+	Name() string
+
+	// Notify delivers the report
+	Notify(ctx context.Context, r report.Report) error
+}
+
+// FromConfig returns the notifiers in report->notify
+func FromConfig(conf configparser.Config) []Notifier {
+
+	notifiers := make([]Notifier, 0, len(conf.Notifiers))
+
+	for _, name := range conf.Notifiers {
+		switch name {
+		case "smtp":
+			notifiers = append(notifiers, NewSMTP(conf))
+		case "webhook":
+			notifiers = append(notifiers, NewWebhook(conf))
+		case "slack":
+			notifiers = append(notifiers, NewSlack(conf))
+		case "telegram":
+			notifiers = append(notifiers, NewTelegram(conf))
+		}
+	}
+
+	return notifiers
+}
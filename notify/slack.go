@@ -0,0 +1,73 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/g0rbe/vps-sentinel/configparser"
+	"github.com/g0rbe/vps-sentinel/render"
+	"github.com/g0rbe/vps-sentinel/report"
+)
+
+// Slack posts a compact summary to a Slack or Mattermost incoming webhook
+type Slack struct {
+	Username string // Overrides the webhook's username, if not empty
+	Channel  string // Overrides the webhook's channel, if not empty
+	Limit    int    // Maximum number of items per list in the summary
+
+	webhook *Webhook
+}
+
+// slackPayload is the body of an incoming webhook, both Slack and Mattermost understand it
+type slackPayload struct {
+	Text     string `json:"text"`
+	Username string `json:"username,omitempty"`
+	Channel  string `json:"channel,omitempty"`
+}
+
+// NewSlack returns a notifier with the settings in the slack section
+func NewSlack(conf configparser.Config) *Slack {
+
+	return &Slack{
+		Username: conf.SlackUsername,
+		Channel:  conf.SlackChannel,
+		Limit:    conf.SlackLimit,
+		webhook: &Webhook{
+			URL:     conf.SlackURL,
+			Retries: 3,
+			Backoff: 5 * time.Second,
+			client:  &http.Client{Timeout: 30 * time.Second}}}
+}
+
+// Name returns "slack"
+func (s *Slack) Name() string {
+	return "slack"
+}
+
+// Notify posts the summary of the report.
+// Incoming webhooks can not upload files, so the full report is not delivered, cut lists are noted in the message.
+func (s *Slack) Notify(ctx context.Context, r report.Report) error {
+
+	brief, truncated := render.Brief(r, s.Limit)
+
+	// Code block keeps the layout and prevents formatting of the log's content
+	text := "```\n" + brief + "```"
+
+	if truncated {
+		text += fmt.Sprintf("\n_Lists are cut to %d items._", s.Limit)
+	}
+
+	body, err := json.Marshal(slackPayload{
+		Text:     text,
+		Username: s.Username,
+		Channel:  s.Channel})
+
+	if err != nil {
+		return fmt.Errorf("failed to encode payload: %s", err)
+	}
+
+	return s.webhook.Send(ctx, body)
+}
@@ -0,0 +1,454 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"io/ioutil"
+	netmail "net/mail"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-mail/mail"
+
+	"github.com/g0rbe/vps-sentinel/configparser"
+	"github.com/g0rbe/vps-sentinel/pgpmime"
+	"github.com/g0rbe/vps-sentinel/render"
+	"github.com/g0rbe/vps-sentinel/report"
+	"github.com/g0rbe/vps-sentinel/spool"
+)
+
+// SMTP sends the report in mail
+type SMTP struct {
+	conf configparser.Config
+}
+
+// NewSMTP returns a notifier with the settings in the smtp section
+func NewSMTP(conf configparser.Config) *SMTP {
+	return &SMTP{conf: conf}
+}
+
+// Name returns "smtp"
+func (s *SMTP) Name() string {
+	return "smtp"
+}
+
+// everyone returns a route to the recipients of every route
+func (s *SMTP) everyone() configparser.Route {
+
+	var all configparser.Route
+
+	for _, route := range s.conf.SMTPRoutes {
+		all.To = append(all.To, route.To...)
+		all.CC = append(all.CC, route.CC...)
+		all.BCC = append(all.BCC, route.BCC...)
+	}
+
+	return all
+}
+
+// NewMessage creates a mail with the headers set from the smtp section.
+// The mail goes to the recipients of every route.
+func (s *SMTP) NewMessage(subject string) *mail.Message {
+	return s.newMessage(subject, s.everyone())
+}
+
+// newMessage creates a mail to the recipients of the route
+func (s *SMTP) newMessage(subject string, route configparser.Route) *mail.Message {
+
+	m := mail.NewMessage()
+	m.SetHeader("From", s.conf.SMTPFrom)
+	m.SetHeader("Subject", subject)
+
+	for header, addresses := range map[string][]string{"To": route.To, "Cc": route.CC, "Bcc": route.BCC} {
+		if len(addresses) > 0 {
+			m.SetHeader(header, addresses...)
+		}
+	}
+
+	return m
+}
+
+// sendmail sends the mails with the sendmail binary
+type sendmail struct {
+	ctx  context.Context
+	path string
+}
+
+// Send pipes the mail to sendmail, the recipients are given as arguments
+func (s sendmail) Send(from string, to []string, msg io.WriterTo) error {
+
+	var buf bytes.Buffer
+
+	if _, err := msg.WriteTo(&buf); err != nil {
+		return fmt.Errorf("failed to write mail: %s", err)
+	}
+
+	args := append([]string{"-oi", "-f", from, "--"}, to...)
+
+	cmd := exec.CommandContext(s.ctx, s.path, args...)
+	cmd.Stdin = &buf
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to run %s: %s, %s", s.path, strings.TrimSpace(string(out)), err)
+	}
+
+	return nil
+}
+
+// Close does nothing, every mail runs its own sendmail
+func (s sendmail) Close() error {
+	return nil
+}
+
+// tlsConfig returns the TLS settings to verify the server with smtp->ca_file and smtp->server_name
+func (s *SMTP) tlsConfig() (*tls.Config, error) {
+
+	config := &tls.Config{ServerName: s.conf.SMTPServerName}
+
+	if s.conf.SMTPCAFile == "" {
+		return config, nil
+	}
+
+	pem, err := ioutil.ReadFile(s.conf.SMTPCAFile)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %s", s.conf.SMTPCAFile, err)
+	}
+
+	config.RootCAs = x509.NewCertPool()
+
+	if !config.RootCAs.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificate found in %s", s.conf.SMTPCAFile)
+	}
+
+	return config, nil
+}
+
+// dial connects to the server in smtp->transport.
+// The returned sender must be closed after the mails are sent.
+func (s *SMTP) dial(ctx context.Context) (mail.SendCloser, error) {
+
+	if s.conf.SMTPTransport == "sendmail" {
+		return sendmail{ctx: ctx, path: s.conf.SMTPSendmail}, nil
+	}
+
+	d := mail.NewDialer(s.conf.SMTPServer, s.conf.SMTPPort, s.conf.SMTPUser, s.conf.SMTPPassword)
+
+	tlsConfig, err := s.tlsConfig()
+
+	if err != nil {
+		return nil, err
+	}
+
+	d.TLSConfig = tlsConfig
+
+	switch s.conf.SMTPTransport {
+	case "starttls":
+		d.SSL = false
+		d.StartTLSPolicy = mail.MandatoryStartTLS
+	case "tls":
+		d.SSL = true
+	case "none":
+		d.SSL = false
+		d.StartTLSPolicy = mail.NoStartTLS
+	}
+
+	return d.Dial()
+}
+
+// Send sends the mail of NewMessage with the transport in smtp->transport,
+// encrypted and signed like the reports
+func (s *SMTP) Send(ctx context.Context, m *mail.Message) error {
+
+	e, err := s.seal(m, s.everyone())
+
+	if err != nil {
+		return err
+	}
+
+	sender, err := s.dial(ctx)
+
+	if err != nil {
+		return err
+	}
+	defer sender.Close()
+
+	return sender.Send(e.from, e.to, bytes.NewReader(e.data))
+}
+
+// reportMessage creates the mail of the report in smtp->format to the recipients of the route,
+// with the templates in report->text_template and report->html_template.
+// The JSON report is attached if report->json contains attachment.
+func (s *SMTP) reportMessage(r report.Report, route configparser.Route) (*mail.Message, error) {
+
+	templates, err := render.LoadTemplates(s.conf.TextTemplate, s.conf.HTMLTemplate)
+
+	if err != nil {
+		return nil, err
+	}
+
+	subject, err := templates.Subject(r)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to render subject: %s", err)
+	}
+
+	m := s.newMessage(subject, route)
+
+	var text, html string
+
+	if s.conf.SMTPFormat == "text" || s.conf.SMTPFormat == "both" {
+		if text, err = templates.Text(r); err != nil {
+			return nil, fmt.Errorf("failed to render text report: %s", err)
+		}
+	}
+
+	if s.conf.SMTPFormat == "html" || s.conf.SMTPFormat == "both" {
+		if html, err = templates.HTML(r); err != nil {
+			return nil, fmt.Errorf("failed to render HTML report: %s", err)
+		}
+	}
+
+	switch s.conf.SMTPFormat {
+	case "text":
+		m.SetBody("text/plain", text)
+	case "html":
+		m.SetBody("text/html", html)
+	case "both":
+		// The preferred format is the last one in multipart/alternative
+		m.SetBody("text/plain", text)
+		m.AddAlternative("text/html", html)
+	}
+
+	for _, output := range s.conf.ReportJSON {
+
+		if output != "attachment" {
+			continue
+		}
+
+		jsonReport, err := render.JSON(r)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to render JSON report: %s", err)
+		}
+
+		m.AttachReader("report.json", bytes.NewReader(jsonReport))
+	}
+
+	return m, nil
+}
+
+// Spool returns the spool of the mails which failed to send
+func (s *SMTP) Spool() *spool.Spool {
+	return spool.New(filepath.Join(s.conf.StatePath, "spool"))
+}
+
+// address returns the bare address for the envelope, eg.: root@example.com for Root <root@example.com>
+func address(value string) string {
+
+	if a, err := netmail.ParseAddress(value); err == nil {
+		return a.Address
+	}
+
+	return value
+}
+
+// envelope is a rendered mail with its sender and every recipient, ready to send or spool
+type envelope struct {
+	subject string
+	from    string
+	to      []string
+	data    []byte
+}
+
+// seal renders the mail of the route, encrypted and signed if smtp->encrypt_to or smtp->sign_key is set.
+// The mail must be rendered only once, the attachments are read while rendering.
+func (s *SMTP) seal(m *mail.Message, route configparser.Route) (envelope, error) {
+
+	var buf bytes.Buffer
+
+	if _, err := m.WriteTo(&buf); err != nil {
+		return envelope{}, fmt.Errorf("failed to write mail: %s", err)
+	}
+
+	keys, err := pgpmime.Load(s.conf.SMTPEncryptTo, s.conf.SMTPSignKey, s.conf.SMTPPassphrase)
+
+	if err != nil {
+		return envelope{}, err
+	}
+
+	data, err := keys.Seal(buf.Bytes())
+
+	if err != nil {
+		return envelope{}, err
+	}
+
+	e := envelope{subject: m.GetHeader("Subject")[0], from: address(s.conf.SMTPFrom), data: data}
+
+	for _, list := range [][]string{route.To, route.CC, route.BCC} {
+		for _, to := range list {
+			e.to = append(e.to, address(to))
+		}
+	}
+
+	return e, nil
+}
+
+// queue stores the mails which failed to send with err in the spool, to retry them later
+func (s *SMTP) queue(envelopes []envelope, sendErr error) error {
+
+	sp := s.Spool()
+
+	for _, e := range envelopes {
+		if _, err := sp.Add(e.from, e.to, e.subject, e.data, sendErr); err != nil {
+			return fmt.Errorf("failed to send mail: %s, failed to queue it: %s", sendErr, err)
+		}
+	}
+
+	return fmt.Errorf("failed to send mail, %d mail(s) queued in %s: %s", len(envelopes), sp.Dir, sendErr)
+}
+
+// Notify sends the report in mail.
+// Every route gets its own mail with its sections only, routes without any of their sections are skipped.
+// The mails which failed to send are stored in the spool, see Flush.
+func (s *SMTP) Notify(ctx context.Context, r report.Report) error {
+
+	envelopes := make([]envelope, 0, len(s.conf.SMTPRoutes))
+
+	for _, route := range s.conf.SMTPRoutes {
+
+		routed := r
+
+		if route.Sections != nil {
+			routed = r.Only(route.Sections)
+		}
+
+		if len(routed.Sections) == 0 || len(route.To)+len(route.CC)+len(route.BCC) == 0 {
+			continue
+		}
+
+		m, err := s.reportMessage(routed, route)
+
+		if err != nil {
+			return err
+		}
+
+		e, err := s.seal(m, route)
+
+		if err != nil {
+			return err
+		}
+
+		envelopes = append(envelopes, e)
+	}
+
+	if len(envelopes) == 0 {
+		return nil
+	}
+
+	sender, err := s.dial(ctx)
+
+	if err != nil {
+		return s.queue(envelopes, err)
+	}
+	defer sender.Close()
+
+	failed := make([]envelope, 0)
+
+	for _, e := range envelopes {
+		if sendErr := sender.Send(e.from, e.to, bytes.NewReader(e.data)); sendErr != nil {
+			failed = append(failed, e)
+			err = sendErr
+		}
+	}
+
+	if len(failed) > 0 {
+		return s.queue(failed, err)
+	}
+
+	return nil
+}
+
+// flushing prevents sending a spooled mail twice from the report and the retries of the daemon
+var flushing sync.Mutex
+
+// Flush retries the spooled mails which are due, or every spooled mail if all is true.
+// A delivered mail is removed from the spool and noted in the next report,
+// a failed one is retried later with a doubled delay.
+// Returns the number of delivered mails.
+func (s *SMTP) Flush(ctx context.Context, all bool) (int, error) {
+
+	flushing.Lock()
+	defer flushing.Unlock()
+
+	sp := s.Spool()
+
+	messages, err := sp.List()
+
+	if err != nil {
+		return 0, err
+	}
+
+	due := make([]spool.Message, 0, len(messages))
+	now := time.Now()
+
+	for _, m := range messages {
+		if all || m.Due(now) {
+			due = append(due, m)
+		}
+	}
+
+	if len(due) == 0 {
+		return 0, nil
+	}
+
+	sender, err := s.dial(ctx)
+
+	if err != nil {
+
+		for _, m := range due {
+			if err := sp.Failed(m, err); err != nil {
+				return 0, err
+			}
+		}
+
+		return 0, fmt.Errorf("failed to send %d spooled mail(s): %s", len(due), err)
+	}
+	defer sender.Close()
+
+	var sendErr error
+	delivered := 0
+
+	for _, m := range due {
+
+		if err := sender.Send(m.From, m.To, bytes.NewReader(m.Data)); err != nil {
+
+			sendErr = err
+
+			if err := sp.Failed(m, err); err != nil {
+				return delivered, err
+			}
+
+			continue
+		}
+
+		if err := sp.Delivered(m); err != nil {
+			return delivered, err
+		}
+
+		delivered++
+	}
+
+	if sendErr != nil {
+		return delivered, fmt.Errorf("failed to send %d spooled mail(s): %s", len(due)-delivered, sendErr)
+	}
+
+	return delivered, nil
+}
@@ -0,0 +1,159 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/g0rbe/vps-sentinel/configparser"
+	"github.com/g0rbe/vps-sentinel/render"
+	"github.com/g0rbe/vps-sentinel/report"
+)
+
+// telegramMaxLength is the maximum length of a message in the Bot API
+const telegramMaxLength = 4096
+
+// Telegram sends a compact summary with the Telegram Bot API.
+// If the summary is cut, the full text report is sent as a document.
+type Telegram struct {
+	APIURL string // Base URL of the Bot API, eg.: https://api.telegram.org
+	Token  string
+	ChatID string
+	Limit  int // Maximum number of items per list in the summary
+
+	client *http.Client
+}
+
+// telegramResponse is the common part of the Bot API's responses
+type telegramResponse struct {
+	OK          bool   `json:"ok"`
+	Description string `json:"description"`
+}
+
+// NewTelegram returns a notifier with the settings in the telegram section
+func NewTelegram(conf configparser.Config) *Telegram {
+
+	return &Telegram{
+		APIURL: strings.TrimSuffix(conf.TelegramAPIURL, "/"),
+		Token:  conf.TelegramToken,
+		ChatID: conf.TelegramChatID,
+		Limit:  conf.TelegramLimit,
+		client: &http.Client{Timeout: 60 * time.Second}}
+}
+
+// Name returns "telegram"
+func (t *Telegram) Name() string {
+	return "telegram"
+}
+
+// call calls a method of the Bot API.
+// The errors never contain the URL, because it contains the token.
+func (t *Telegram) call(ctx context.Context, method, contentType string, body io.Reader) error {
+
+	url := fmt.Sprintf("%s/bot%s/%s", t.APIURL, t.Token, method)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, body)
+
+	if err != nil {
+		return fmt.Errorf("failed to create %s request", method)
+	}
+
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("User-Agent", "vps-sentinel")
+
+	resp, err := t.client.Do(req)
+
+	if err != nil {
+		// The error of the client contains the URL
+		if ctx.Err() != nil {
+			return fmt.Errorf("failed to call %s: %s", method, ctx.Err())
+		}
+
+		return fmt.Errorf("failed to call %s: request failed", method)
+	}
+
+	defer resp.Body.Close()
+
+	var result telegramResponse
+
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&result); err != nil {
+		return fmt.Errorf("failed to call %s: %s: invalid response: %s", method, resp.Status, err)
+	}
+
+	if !result.OK {
+		return fmt.Errorf("failed to call %s: %s: %s", method, resp.Status, result.Description)
+	}
+
+	return nil
+}
+
+// sendMessage sends a text message to the chat
+func (t *Telegram) sendMessage(ctx context.Context, text string) error {
+
+	if runes := []rune(text); len(runes) > telegramMaxLength {
+		text = string(runes[:telegramMaxLength-4]) + "\n..."
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"chat_id":                  t.ChatID,
+		"text":                     text,
+		"disable_web_page_preview": true})
+
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %s", err)
+	}
+
+	return t.call(ctx, "sendMessage", "application/json", bytes.NewReader(body))
+}
+
+// sendDocument sends a file to the chat
+func (t *Telegram) sendDocument(ctx context.Context, name string, content []byte) error {
+
+	var body bytes.Buffer
+
+	w := multipart.NewWriter(&body)
+
+	if err := w.WriteField("chat_id", t.ChatID); err != nil {
+		return fmt.Errorf("failed to write chat_id: %s", err)
+	}
+
+	part, err := w.CreateFormFile("document", name)
+
+	if err != nil {
+		return fmt.Errorf("failed to create document: %s", err)
+	}
+
+	if _, err := part.Write(content); err != nil {
+		return fmt.Errorf("failed to write document: %s", err)
+	}
+
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to close multipart body: %s", err)
+	}
+
+	return t.call(ctx, "sendDocument", w.FormDataContentType(), &body)
+}
+
+// Notify sends the summary, and the full text report if the summary is cut
+func (t *Telegram) Notify(ctx context.Context, r report.Report) error {
+
+	text, truncated := render.Brief(r, t.Limit)
+
+	if err := t.sendMessage(ctx, text); err != nil {
+		return err
+	}
+
+	if !truncated {
+		return nil
+	}
+
+	name := fmt.Sprintf("report-%s.txt", r.Time.Format("2006-01-02"))
+
+	return t.sendDocument(ctx, name, []byte(render.Text(r)))
+}
@@ -0,0 +1,158 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/g0rbe/vps-sentinel/configparser"
+	"github.com/g0rbe/vps-sentinel/render"
+	"github.com/g0rbe/vps-sentinel/report"
+)
+
+// SignatureHeader is the header of the body's HMAC-SHA256 signature, if webhook->secret is set.
+// The value is "sha256=" followed by the hex encoded signature.
+const SignatureHeader = "X-Vps-Sentinel-Signature"
+
+// Webhook POSTs the JSON report or its summary to an URL
+type Webhook struct {
+	URL     string
+	Payload string // report or summary
+	Headers map[string]string
+	Secret  string
+	Retries int           // Number of retries after the first attempt
+	Backoff time.Duration // Delay before the first retry, doubled after every retry
+
+	client *http.Client
+}
+
+// NewWebhook returns a notifier with the settings in the webhook section
+func NewWebhook(conf configparser.Config) *Webhook {
+
+	return &Webhook{
+		URL:     conf.WebhookURL,
+		Payload: conf.WebhookPayload,
+		Headers: conf.WebhookHeaders,
+		Secret:  conf.WebhookSecret,
+		Retries: conf.WebhookRetries,
+		Backoff: 5 * time.Second,
+		client:  &http.Client{Timeout: conf.WebhookTimeout}}
+}
+
+// Name returns "webhook"
+func (w *Webhook) Name() string {
+	return "webhook"
+}
+
+// Sign returns the value of SignatureHeader for the body
+func Sign(secret string, body []byte) string {
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// retryableError is an error which worth another attempt
+type retryableError struct {
+	err error
+}
+
+func (e retryableError) Error() string {
+	return e.err.Error()
+}
+
+// post sends the body once
+func (w *Webhook) post(ctx context.Context, body []byte) error {
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+
+	if err != nil {
+		return fmt.Errorf("failed to create request: %s", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "vps-sentinel")
+
+	for name, value := range w.Headers {
+		req.Header.Set(name, value)
+	}
+
+	if w.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(w.Secret, body))
+	}
+
+	resp, err := w.client.Do(req)
+
+	if err != nil {
+		return retryableError{err}
+	}
+
+	defer resp.Body.Close()
+
+	// Read the body to reuse the connection
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 1<<20))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return retryableError{fmt.Errorf("unexpected status: %s", resp.Status)}
+	}
+
+	return fmt.Errorf("unexpected status: %s", resp.Status)
+}
+
+// Send POSTs the body to the URL, retries with exponential backoff on network errors,
+// 5XX and 429 responses.
+func (w *Webhook) Send(ctx context.Context, body []byte) error {
+
+	delay := w.Backoff
+
+	for attempt := 0; ; attempt++ {
+
+		err := w.post(ctx, body)
+
+		if err == nil {
+			return nil
+		}
+
+		if _, ok := err.(retryableError); !ok || attempt >= w.Retries {
+			return fmt.Errorf("failed to POST to %s after %d attempt(s): %s", w.URL, attempt+1, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("failed to POST to %s: %s (last error: %s)", w.URL, ctx.Err(), err)
+		case <-time.After(delay):
+		}
+
+		delay *= 2
+	}
+}
+
+// Notify POSTs the report in webhook->payload format
+func (w *Webhook) Notify(ctx context.Context, r report.Report) error {
+
+	var body []byte
+	var err error
+
+	if w.Payload == "summary" {
+		body, err = render.JSONSummary(r)
+	} else {
+		body, err = render.JSON(r)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to render JSON: %s", err)
+	}
+
+	return w.Send(ctx, body)
+}
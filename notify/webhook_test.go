@@ -0,0 +1,225 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/g0rbe/vps-sentinel/report"
+)
+
+// testWebhook returns a webhook to the URL that retries quickly
+func testWebhook(url string) *Webhook {
+
+	return &Webhook{
+		URL:     url,
+		Payload: "report",
+		Headers: make(map[string]string),
+		Retries: 3,
+		Backoff: 5 * time.Millisecond,
+		client:  &http.Client{Timeout: 5 * time.Second}}
+}
+
+// testReport returns a report with one warning
+func testReport() report.Report {
+
+	return report.Report{
+		Kind: "report",
+		Host: "test.example.com",
+		Time: time.Date(2026, 10, 18, 4, 0, 0, 0, time.UTC),
+		Sections: []report.Section{{
+			ID:       "system",
+			Title:    "System informations",
+			Status:   report.StatusOK,
+			Findings: []report.Finding{{Severity: report.Warning, Message: "Memory usage is 85.0%"}}}}}
+}
+
+func TestSign(t *testing.T) {
+
+	// RFC 4231, test case 2
+	want := "sha256=5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843"
+
+	if got := Sign("Jefe", []byte("what do ya want for nothing?")); got != want {
+		t.Fatalf("Sign() = %s, want %s", got, want)
+	}
+}
+
+func TestWebhookHeaders(t *testing.T) {
+
+	var header http.Header
+	var body []byte
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		body, _ = ioutil.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	w := testWebhook(server.URL)
+	w.Secret = "secret"
+	w.Headers["Authorization"] = "Bearer XXX"
+	w.Headers["X-Env"] = "prod"
+
+	if err := w.Notify(context.Background(), testReport()); err != nil {
+		t.Fatalf("Notify() failed: %s", err)
+	}
+
+	if got := header.Get(SignatureHeader); got != Sign("secret", body) {
+		t.Errorf("%s = %s, want %s", SignatureHeader, got, Sign("secret", body))
+	}
+
+	for name, want := range map[string]string{
+		"Authorization": "Bearer XXX",
+		"X-Env":         "prod",
+		"Content-Type":  "application/json",
+	} {
+		if got := header.Get(name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestWebhookNoSignature(t *testing.T) {
+
+	var header http.Header
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+	}))
+	defer server.Close()
+
+	if err := testWebhook(server.URL).Notify(context.Background(), testReport()); err != nil {
+		t.Fatalf("Notify() failed: %s", err)
+	}
+
+	if got := header.Get(SignatureHeader); got != "" {
+		t.Errorf("%s = %s without secret", SignatureHeader, got)
+	}
+}
+
+func TestWebhookRetry(t *testing.T) {
+
+	for _, status := range []int{http.StatusInternalServerError, http.StatusBadGateway, http.StatusTooManyRequests} {
+
+		var attempts int32
+
+		// Fails twice, then succeeds
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&attempts, 1) <= 2 {
+				w.WriteHeader(status)
+			}
+		}))
+
+		start := time.Now()
+		err := testWebhook(server.URL).Send(context.Background(), []byte("{}"))
+		elapsed := time.Since(start)
+
+		server.Close()
+
+		if err != nil {
+			t.Errorf("status %d: Send() failed: %s", status, err)
+		}
+
+		if attempts != 3 {
+			t.Errorf("status %d: %d attempt(s), want 3", status, attempts)
+		}
+
+		// 5ms before the first retry, 10ms before the second one
+		if elapsed < 15*time.Millisecond {
+			t.Errorf("status %d: retried after %s, want at least 15ms of backoff", status, elapsed)
+		}
+	}
+}
+
+func TestWebhookRetryGiveUp(t *testing.T) {
+
+	var attempts int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	if err := testWebhook(server.URL).Send(context.Background(), []byte("{}")); err == nil {
+		t.Fatalf("Send() succeeded on 503")
+	}
+
+	// The first attempt and 3 retries
+	if attempts != 4 {
+		t.Errorf("%d attempt(s), want 4", attempts)
+	}
+}
+
+func TestWebhookNoRetry(t *testing.T) {
+
+	for _, status := range []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound} {
+
+		var attempts int32
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&attempts, 1)
+			w.WriteHeader(status)
+		}))
+
+		err := testWebhook(server.URL).Send(context.Background(), []byte("{}"))
+
+		server.Close()
+
+		if err == nil {
+			t.Errorf("status %d: Send() succeeded", status)
+		}
+
+		if attempts != 1 {
+			t.Errorf("status %d: %d attempt(s), want 1", status, attempts)
+		}
+	}
+}
+
+func TestWebhookPayload(t *testing.T) {
+
+	for _, payload := range []string{"report", "summary"} {
+
+		var body map[string]interface{}
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			json.NewDecoder(r.Body).Decode(&body)
+		}))
+
+		w := testWebhook(server.URL)
+		w.Payload = payload
+
+		err := w.Notify(context.Background(), testReport())
+
+		server.Close()
+
+		if err != nil {
+			t.Fatalf("%s: Notify() failed: %s", payload, err)
+		}
+
+		if body["host"] != "test.example.com" {
+			t.Errorf("%s: host = %v", payload, body["host"])
+		}
+
+		_, hasSections := body["sections"]
+		_, hasSummary := body["summary"]
+		issues, _ := body["issues"].([]interface{})
+
+		switch payload {
+		case "report":
+			if !hasSections || hasSummary {
+				t.Errorf("report payload has sections: %t, summary: %t", hasSections, hasSummary)
+			}
+		case "summary":
+			if hasSections || !hasSummary || len(issues) != 1 || body["severity"] != "warning" {
+				t.Errorf("summary payload has sections: %t, summary: %t, %d issue(s), severity %v",
+					hasSections, hasSummary, len(issues), body["severity"])
+			}
+		}
+	}
+}
@@ -0,0 +1,243 @@
+// Package pgpmime encrypts and signs mails as PGP/MIME messages (RFC 3156)
+package pgpmime
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+// config of the signatures, micalg of multipart/signed must match the hash
+var config = &packet.Config{DefaultHash: crypto.SHA256}
+
+const micalg = "pgp-sha256"
+
+// Keys are the public keys to encrypt to and the private key to sign with
+type Keys struct {
+	Recipients openpgp.EntityList // Empty if the mails are not encrypted
+	Signer     *openpgp.Entity    // Nil if the mails are not signed
+}
+
+// readKeyRing reads the armored or binary keys in the file
+func readKeyRing(path string) (openpgp.EntityList, error) {
+
+	content, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %s", path, err)
+	}
+
+	keys, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(content))
+
+	if err != nil {
+		keys, err = openpgp.ReadKeyRing(bytes.NewReader(content))
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key from %s: %s", path, err)
+	}
+
+	return keys, nil
+}
+
+// Load reads the public keys in the files of encryptTo, and the private key in signKey
+// decrypted with passphrase. Both may be empty.
+func Load(encryptTo []string, signKey, passphrase string) (*Keys, error) {
+
+	k := &Keys{}
+
+	for _, path := range encryptTo {
+
+		keys, err := readKeyRing(path)
+
+		if err != nil {
+			return nil, err
+		}
+
+		// Fails if a key can not encrypt, eg.: expired or revoked
+		if _, err := openpgp.Encrypt(ioutil.Discard, keys, nil, nil, config); err != nil {
+			return nil, fmt.Errorf("failed to encrypt with the key in %s: %s", path, err)
+		}
+
+		k.Recipients = append(k.Recipients, keys...)
+	}
+
+	if signKey == "" {
+		return k, nil
+	}
+
+	keys, err := readKeyRing(signKey)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(keys) != 1 || keys[0].PrivateKey == nil {
+		return nil, fmt.Errorf("%s must contain exactly one private key", signKey)
+	}
+
+	k.Signer = keys[0]
+
+	private := []*packet.PrivateKey{k.Signer.PrivateKey}
+
+	for _, subkey := range k.Signer.Subkeys {
+		if subkey.PrivateKey != nil {
+			private = append(private, subkey.PrivateKey)
+		}
+	}
+
+	for _, key := range private {
+		if key.Encrypted {
+			if err := key.Decrypt([]byte(passphrase)); err != nil {
+				return nil, fmt.Errorf("failed to decrypt the key in %s: %s", signKey, err)
+			}
+		}
+	}
+
+	return k, nil
+}
+
+// Enabled reports whether the mails are encrypted or signed
+func (k *Keys) Enabled() bool {
+	return len(k.Recipients) > 0 || k.Signer != nil
+}
+
+// split returns the header fields of the message with their folded lines,
+// and the body after the empty line
+func split(message []byte) ([]string, []byte, error) {
+
+	end := bytes.Index(message, []byte("\r\n\r\n"))
+
+	if end < 0 {
+		return nil, nil, fmt.Errorf("no end of header found")
+	}
+
+	fields := make([]string, 0)
+
+	for _, line := range strings.Split(string(message[:end]), "\r\n") {
+
+		if len(fields) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			fields[len(fields)-1] += "\r\n" + line
+			continue
+		}
+
+		fields = append(fields, line)
+	}
+
+	return fields, message[end+4:], nil
+}
+
+// boundary returns a random MIME boundary
+func boundary() (string, error) {
+
+	b := make([]byte, 16)
+
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+// crlf converts the line endings of the armored text to CRLF
+func crlf(text string) string {
+	return strings.Replace(strings.TrimRight(text, "\n"), "\n", "\r\n", -1) + "\r\n"
+}
+
+// Seal encrypts the message to the recipients and signs it with the signer.
+// The message is a whole mail with CRLF line endings, eg.: written by go-mail.
+// The Content-* header fields and the body are protected, the other fields, eg.: Subject, remain readable.
+func (k *Keys) Seal(message []byte) ([]byte, error) {
+
+	if !k.Enabled() {
+		return message, nil
+	}
+
+	fields, body, err := split(message)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse mail: %s", err)
+	}
+
+	// The MIME entity of the content, and the header fields that stay outside
+	var entity, outer bytes.Buffer
+
+	for _, field := range fields {
+		if strings.HasPrefix(strings.ToLower(field), "content-") {
+			entity.WriteString(field + "\r\n")
+		} else {
+			outer.WriteString(field + "\r\n")
+		}
+	}
+
+	entity.WriteString("\r\n")
+	entity.Write(body)
+
+	b, err := boundary()
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to create boundary: %s", err)
+	}
+
+	if len(k.Recipients) > 0 {
+
+		var encrypted bytes.Buffer
+
+		armored, err := armor.Encode(&encrypted, "PGP MESSAGE", nil)
+
+		if err != nil {
+			return nil, err
+		}
+
+		// Signed and encrypted in one OpenPGP message, RFC 3156 6.2
+		plain, err := openpgp.Encrypt(armored, k.Recipients, k.Signer, nil, config)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt mail: %s", err)
+		}
+
+		plain.Write(entity.Bytes())
+
+		if err := plain.Close(); err != nil {
+			return nil, fmt.Errorf("failed to encrypt mail: %s", err)
+		}
+
+		if err := armored.Close(); err != nil {
+			return nil, fmt.Errorf("failed to encrypt mail: %s", err)
+		}
+
+		fmt.Fprintf(&outer, "Content-Type: multipart/encrypted; protocol=\"application/pgp-encrypted\";\r\n boundary=\"%s\"\r\n\r\n", b)
+		fmt.Fprintf(&outer, "This is an OpenPGP/MIME encrypted message (RFC 4880 and 3156)\r\n")
+		fmt.Fprintf(&outer, "--%s\r\nContent-Type: application/pgp-encrypted\r\nContent-Description: PGP/MIME version identification\r\n\r\nVersion: 1\r\n\r\n", b)
+		fmt.Fprintf(&outer, "--%s\r\nContent-Type: application/octet-stream; name=\"encrypted.asc\"\r\n", b)
+		fmt.Fprintf(&outer, "Content-Description: OpenPGP encrypted message\r\nContent-Disposition: inline; filename=\"encrypted.asc\"\r\n\r\n")
+		fmt.Fprintf(&outer, "%s--%s--\r\n", crlf(encrypted.String()), b)
+
+		return outer.Bytes(), nil
+	}
+
+	// Signed only, the signature is over the entity exactly as it is sent, RFC 3156 5
+	var signature bytes.Buffer
+
+	if err := openpgp.ArmoredDetachSign(&signature, k.Signer, bytes.NewReader(entity.Bytes()), config); err != nil {
+		return nil, fmt.Errorf("failed to sign mail: %s", err)
+	}
+
+	fmt.Fprintf(&outer, "Content-Type: multipart/signed; micalg=%s; protocol=\"application/pgp-signature\";\r\n boundary=\"%s\"\r\n\r\n", micalg, b)
+	fmt.Fprintf(&outer, "This is an OpenPGP/MIME signed message (RFC 4880 and 3156)\r\n")
+	fmt.Fprintf(&outer, "--%s\r\n", b)
+	outer.Write(entity.Bytes())
+	fmt.Fprintf(&outer, "\r\n--%s\r\nContent-Type: application/pgp-signature; name=\"signature.asc\"\r\n", b)
+	fmt.Fprintf(&outer, "Content-Description: OpenPGP digital signature\r\nContent-Disposition: attachment; filename=\"signature.asc\"\r\n\r\n")
+	fmt.Fprintf(&outer, "%s--%s--\r\n", crlf(signature.String()), b)
+
+	return outer.Bytes(), nil
+}
@@ -0,0 +1,44 @@
+package port
+
+import (
+	"context"
+
+	"github.com/g0rbe/vps-sentinel/collector"
+	"github.com/g0rbe/vps-sentinel/configparser"
+	"github.com/g0rbe/vps-sentinel/report"
+)
+
+// portCollector is the "port" feature of the report
+type portCollector struct{}
+
+func init() {
+	collector.Register(portCollector{})
+}
+
+func (portCollector) Name() string {
+	return "port"
+}
+
+func (portCollector) Title() string {
+	return "Open ports"
+}
+
+func (portCollector) Section() string {
+	return "port"
+}
+
+// Collect creates a section for every protocol in port->protocol
+func (portCollector) Collect(ctx context.Context, conf configparser.Config) []report.Section {
+
+	sections := make([]report.Section, 0, len(conf.PortProtocol))
+
+	for _, protocol := range conf.PortProtocol {
+
+		section, err := GetListeningPorts(protocol)
+
+		sections = append(sections, collector.NewSection(
+			"port:"+protocol, "Open ports ("+protocol+")", section, err))
+	}
+
+	return sections
+}
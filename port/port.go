@@ -5,16 +5,17 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"syscall"
 
-	"github.com/jedib0t/go-pretty/table"
+	"github.com/g0rbe/vps-sentinel/report"
 )
 
 // sockinfo holds informations about listening port and its related process name
 type portInfo struct {
-	PortNo   string
+	PortNo   int
 	ProcName string
 }
 
@@ -136,19 +137,19 @@ func parsePorts(protocol string) ([]portInfo, error) {
 				return nil, fmt.Errorf("failed to convert %s to int: %s", portStrHex, err)
 			}
 
-			portStr := strconv.Itoa(int(portInt))
+			portNo := int(portInt)
 
 			// Check wether the current port is exist in the list to disbale duplication
 			isExist := false
 
 			for _, v := range result {
-				if v.PortNo == portStr {
+				if v.PortNo == portNo {
 					isExist = true
 				}
 			}
 
 			if !isExist {
-				result = append(result, portInfo{PortNo: portStr, ProcName: procName})
+				result = append(result, portInfo{PortNo: portNo, ProcName: procName})
 			}
 		}
 	}
@@ -161,27 +162,27 @@ func parsePorts(protocol string) ([]portInfo, error) {
 }
 
 // GetListeningPorts generates a table report of open ports and its related process
-func GetListeningPorts(protocol string) (string, error) {
+// The ports are sorted in ascending order
+func GetListeningPorts(protocol string) (report.Section, error) {
+
+	var section report.Section
 
 	ports, err := parsePorts(protocol)
 
 	if err != nil {
-		return "", fmt.Errorf("failed to parse ports: %s", err)
+		return section, fmt.Errorf("failed to parse ports: %s", err)
 	}
 
-	t := table.NewWriter()
+	sort.Slice(ports, func(i, j int) bool { return ports[i].PortNo < ports[j].PortNo })
 
-	t.AppendHeader(table.Row{"Port", "Process"})
+	section.Table = report.NewTable(
+		report.Column{Name: "Port", Type: report.Int},
+		report.Column{Name: "Process", Type: report.String})
+	section.Table.Key = []string{"Port", "Process"}
 
 	for _, port := range ports {
-		t.AppendRow(table.Row{port.PortNo, port.ProcName})
+		section.Table.Append(port.PortNo, port.ProcName)
 	}
 
-	sort := []table.SortBy{table.SortBy{Name: "Port", Mode: table.AscNumeric}}
-
-	t.SortBy(sort)
-
-	result := t.Render() + "\n\n"
-
-	return result, nil
+	return section, nil
 }
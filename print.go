@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/g0rbe/vps-sentinel/metrics"
+	"github.com/g0rbe/vps-sentinel/render"
+)
+
+// printCmd collects the report and prints it to the standard output without sending it.
+// The text and HTML reports use the templates in report->text_template and report->html_template.
+func printCmd(args []string) int {
+
+	var opts options
+
+	fs := flag.NewFlagSet("print", flag.ExitOnError)
+	opts.register(fs)
+	format := fs.String("format", "text", "format of the report: text, html, json or metrics")
+	fs.Parse(args)
+
+	if *format != "text" && *format != "html" && *format != "json" && *format != "metrics" {
+		fmt.Fprintf(os.Stderr, "Invalid format: %s\n", *format)
+		return 2
+	}
+
+	conf, err := opts.loadConfig()
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to parse configuration file: %s\n", err)
+		return 1
+	}
+
+	// The report goes to the standard output, so progress goes to the standard error
+	r := collectReport(context.Background(), conf, os.Stderr)
+
+	templates, err := render.LoadTemplates(conf.TextTemplate, conf.HTMLTemplate)
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load templates: %s\n", err)
+		return 1
+	}
+
+	switch *format {
+	case "text":
+		text, err := templates.Text(r)
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to render text report: %s\n", err)
+			return 1
+		}
+
+		fmt.Print(text)
+	case "html":
+		html, err := templates.HTML(r)
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to render HTML report: %s\n", err)
+			return 1
+		}
+
+		fmt.Print(html)
+	case "json":
+		out, err := render.JSON(r)
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to render JSON report: %s\n", err)
+			return 1
+		}
+
+		fmt.Printf("%s\n", out)
+	case "metrics":
+		if err := metrics.Write(os.Stdout, r.Sections, conf.MetricsTop); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write metrics: %s\n", err)
+			return 1
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,35 @@
+package process
+
+import (
+	"context"
+
+	"github.com/g0rbe/vps-sentinel/collector"
+	"github.com/g0rbe/vps-sentinel/configparser"
+	"github.com/g0rbe/vps-sentinel/report"
+)
+
+// procCollector is the "process" feature of the report
+type procCollector struct{}
+
+func init() {
+	collector.Register(procCollector{})
+}
+
+func (procCollector) Name() string {
+	return "process"
+}
+
+func (procCollector) Title() string {
+	return "List of processes"
+}
+
+func (procCollector) Section() string {
+	return "process"
+}
+
+func (procCollector) Collect(ctx context.Context, conf configparser.Config) []report.Section {
+
+	section, err := GetReport(conf.ProcessSort)
+
+	return []report.Section{collector.NewSection("process", "List of processes", section, err)}
+}
@@ -111,18 +111,19 @@ func getTimeStatFromPid(pid string) (utime, stime, cutime, cstime, starttime flo
 }
 
 // getCPUUsageFromPid calcultes the CPU usage of the given process.
-func getCPUUsageFromPid(pid string) (string, error) {
+// Returns the usage in percent.
+func getCPUUsageFromPid(pid string) (float64, error) {
 
 	uptime, err := getUpTime()
 
 	if err != nil {
-		return "", fmt.Errorf("failed to get uptime: %s", err)
+		return 0, fmt.Errorf("failed to get uptime: %s", err)
 	}
 
 	utime, stime, cutime, cstime, starttime, err := getTimeStatFromPid(pid)
 
 	if err != nil {
-		return "", fmt.Errorf("failed to get time stats: %s", err)
+		return 0, fmt.Errorf("failed to get time stats: %s", err)
 	}
 
 	hertz := getHertz()
@@ -131,7 +132,7 @@ func getCPUUsageFromPid(pid string) (string, error) {
 
 	seconds := uptime - (starttime / hertz)
 
-	cpuUsage := fmt.Sprintf("%.3f", 100*((totalTime/hertz)/seconds))
+	cpuUsage := 100 * ((totalTime / hertz) / seconds)
 
 	return cpuUsage, nil
 }
@@ -9,8 +9,8 @@ import (
 )
 
 // getMemoryUsageFromPid calculates the given PID's memory usage based on PSS.
-// Returns the memory usage in byte.
-func getMemoryUsageFromPid(pid string) (string, error) {
+// Returns the memory usage in MiB.
+func getMemoryUsageFromPid(pid string) (int, error) {
 
 	totalSize := 0
 
@@ -19,7 +19,7 @@ func getMemoryUsageFromPid(pid string) (string, error) {
 	smapsFile, err := os.Open(smapsFilePath)
 
 	if err != nil {
-		return "", fmt.Errorf("failed to open %s: %s", smapsFilePath, err)
+		return 0, fmt.Errorf("failed to open %s: %s", smapsFilePath, err)
 	}
 
 	defer smapsFile.Close()
@@ -35,12 +35,12 @@ func getMemoryUsageFromPid(pid string) (string, error) {
 			partSize, err := strconv.Atoi(fields[1])
 
 			if err != nil {
-				return "", fmt.Errorf("failed to convert %s to int: %s", fields[1], err)
+				return 0, fmt.Errorf("failed to convert %s to int: %s", fields[1], err)
 			}
 
 			totalSize += partSize
 		}
 	}
 
-	return strconv.Itoa(totalSize / 1024), nil
+	return totalSize / 1024, nil
 }
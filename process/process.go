@@ -5,11 +5,12 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"syscall"
 
-	"github.com/jedib0t/go-pretty/table"
+	"github.com/g0rbe/vps-sentinel/report"
 )
 
 // ProcInfo stores informations about one process
@@ -17,8 +18,8 @@ type ProcInfo struct {
 	Pid         string
 	Name        string
 	User        string
-	CPUUsage    string
-	MemoryUsage string
+	CPUUsage    float64 // In percent
+	MemoryUsage int     // In MiB
 }
 
 // getNameFromPid returns the process name that associated with the given pid.
@@ -134,45 +135,62 @@ func listProcesses() ([]ProcInfo, error) {
 	return procInfoArray, nil
 }
 
-// GetReport returns the report of processes
-func GetReport(sortField string) (string, error) {
-
-	procInfos, err := listProcesses()
-
-	if err != nil {
-		return "", fmt.Errorf("failed to list processes: %s", err)
-	}
+// sortProcesses sorts the processes by the given field.
+// pid / name / user sorted in ascending, cpu / memory sorted in descending order.
+func sortProcesses(procInfos []ProcInfo, sortField string) {
 
-	var sort []table.SortBy
+	var less func(a, b ProcInfo) bool
 
 	switch sortField {
 	case "pid":
-		sort = append(sort, table.SortBy{Name: "Pid", Mode: table.AscNumeric})
+		less = func(a, b ProcInfo) bool {
+			aPid, _ := strconv.Atoi(a.Pid)
+			bPid, _ := strconv.Atoi(b.Pid)
+			return aPid < bPid
+		}
 	case "name":
-		sort = append(sort, table.SortBy{Name: "Name", Mode: table.Asc})
+		less = func(a, b ProcInfo) bool { return a.Name < b.Name }
 	case "user":
-		sort = append(sort, table.SortBy{Name: "User", Mode: table.Asc})
+		less = func(a, b ProcInfo) bool { return a.User < b.User }
 	case "cpu":
-		sort = append(sort, table.SortBy{Name: "CPU", Mode: table.DscNumeric})
+		less = func(a, b ProcInfo) bool { return a.CPUUsage > b.CPUUsage }
 	case "memory":
-		sort = append(sort, table.SortBy{Name: "Memory (MiB)", Mode: table.DscNumeric})
+		less = func(a, b ProcInfo) bool { return a.MemoryUsage > b.MemoryUsage }
+	default:
+		return
 	}
 
-	t := table.NewWriter()
+	sort.SliceStable(procInfos, func(i, j int) bool { return less(procInfos[i], procInfos[j]) })
+}
 
-	t.AppendHeader(table.Row{"Pid", "Name", "User", "CPU", "Memory (MiB)"})
+// GetReport returns the report of processes
+func GetReport(sortField string) (report.Section, error) {
 
-	for _, procInfo := range procInfos {
+	var section report.Section
 
-		t.AppendRow(table.Row{procInfo.Pid, procInfo.Name, procInfo.User,
-			procInfo.CPUUsage, procInfo.MemoryUsage})
+	procInfos, err := listProcesses()
 
+	if err != nil {
+		return section, fmt.Errorf("failed to list processes: %s", err)
 	}
 
-	t.SortBy(sort)
+	sortProcesses(procInfos, sortField)
 
-	report := t.Render() + "\n\n"
+	section.Table = report.NewTable(
+		report.Column{Name: "Pid", Type: report.Int},
+		report.Column{Name: "Name", Type: report.String},
+		report.Column{Name: "User", Type: report.String},
+		report.Column{Name: "CPU", Type: report.Float},
+		report.Column{Name: "Memory (MiB)", Type: report.Int})
+	section.Table.Key = []string{"Name", "User"}
 
-	return report, nil
+	for _, procInfo := range procInfos {
+
+		pid, _ := strconv.Atoi(procInfo.Pid)
+
+		section.Table.Append(pid, procInfo.Name, procInfo.User,
+			procInfo.CPUUsage, procInfo.MemoryUsage)
+	}
 
+	return section, nil
 }
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/g0rbe/vps-sentinel/notify"
+)
+
+// queueCmd lists, flushes or drops the mails in the spool
+func queueCmd(args []string) int {
+
+	var opts options
+
+	fs := flag.NewFlagSet("queue", flag.ExitOnError)
+	opts.register(fs)
+	all := fs.Bool("all", false, "drop every spooled mail")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: vps-sentinel queue [flags] list|flush|drop <id>...\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() < 1 {
+		fs.Usage()
+		return 2
+	}
+
+	// The flags may follow the action too, eg.: queue drop --all
+	action := fs.Arg(0)
+	fs.Parse(fs.Args()[1:])
+
+	conf, err := opts.loadConfig()
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to parse configuration file: %s\n", err)
+		return 1
+	}
+
+	smtp := notify.NewSMTP(conf)
+	sp := smtp.Spool()
+
+	messages, err := sp.List()
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to list spooled mails: %s\n", err)
+		return 1
+	}
+
+	switch action {
+	case "list":
+
+		if len(messages) == 0 {
+			fmt.Printf("No mails in %s\n", sp.Dir)
+			return 0
+		}
+
+		for _, m := range messages {
+			fmt.Printf("%s  %s  to %s\n", m.ID, m.Subject, strings.Join(m.To, ", "))
+			fmt.Printf("    queued %s, %d attempt(s), next %s\n    %s\n",
+				m.Queued.Format(time.RFC1123), m.Attempts, m.NextAttempt.Format(time.RFC1123), m.LastError)
+		}
+
+	case "flush":
+
+		// Everything is sent now, regardless of the backoff
+		delivered, err := smtp.Flush(context.Background(), true)
+
+		fmt.Printf("Delivered %d of %d spooled mail(s)\n", delivered, len(messages))
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			return 1
+		}
+
+	case "drop":
+
+		ids := fs.Args()
+
+		if *all {
+			ids = make([]string, 0, len(messages))
+
+			for _, m := range messages {
+				ids = append(ids, m.ID)
+			}
+		} else if len(ids) == 0 {
+			fs.Usage()
+			return 2
+		}
+
+		for _, id := range ids {
+
+			if err := sp.Drop(id); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to drop mail: %s\n", err)
+				return 1
+			}
+
+			fmt.Printf("Dropped %s\n", id)
+		}
+
+	default:
+		fs.Usage()
+		return 2
+	}
+
+	return 0
+}
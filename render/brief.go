@@ -0,0 +1,88 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/g0rbe/vps-sentinel/report"
+)
+
+// briefList renders a titled list with at most limit items.
+// Returns empty string for empty list, and true if items are left out.
+func briefList(title string, items []string, limit int) (string, bool) {
+
+	if len(items) == 0 {
+		return "", false
+	}
+
+	text := title + ":\n"
+
+	for i, item := range items {
+
+		if i == limit {
+			text += fmt.Sprintf("• ... and %d more\n", len(items)-limit)
+			return text, true
+		}
+
+		text += "• " + item + "\n"
+	}
+
+	return text, false
+}
+
+// Brief renders a compact summary of the report for chat messages:
+// the issues, the new ports, the IPs with the most failed SSH logins and the ClamAV hits.
+// Every list is cut to limit items, the returned bool is true if anything is left out.
+func Brief(r report.Report, limit int) (string, bool) {
+
+	var newPorts, failedLogins, clamavHits []string
+
+	for _, section := range r.Sections {
+
+		switch {
+		case section.Collector == "port" && section.Changes != nil:
+			for _, added := range section.Changes.Added {
+				newPorts = append(newPorts, strings.TrimPrefix(section.ID, "port:")+" "+added)
+			}
+		case section.ID == "log.ssh:failed" && section.Table != nil:
+			// The table is sorted by the count in descending order
+			for _, row := range section.Table.Rows {
+				failedLogins = append(failedLogins, fmt.Sprintf("%v (%v)", row[0], row[1]))
+			}
+		case section.Collector == "clamav" && section.Table != nil:
+			for _, row := range section.Table.Rows {
+				clamavHits = append(clamavHits, fmt.Sprintf("%v: %v", row[0], row[1]))
+			}
+		}
+	}
+
+	issues := make([]string, 0)
+
+	for _, issue := range r.Issues() {
+		issues = append(issues, fmt.Sprintf("[%s] %s: %s",
+			strings.ToUpper(issue.Severity.String()), issue.Section, issue.Message))
+	}
+
+	text := fmt.Sprintf("[%s] %s\n", r.Host, Summary(r))
+	truncated := false
+
+	for _, list := range []struct {
+		title string
+		items []string
+	}{
+		{"Issues", issues},
+		{"New ports", newPorts},
+		{"Top failed SSH logins", failedLogins},
+		{"ClamAV hits", clamavHits},
+	} {
+		part, cut := briefList(list.title, list.items, limit)
+
+		if part != "" {
+			text += "\n" + part
+		}
+
+		truncated = truncated || cut
+	}
+
+	return text, truncated
+}
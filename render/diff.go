@@ -0,0 +1,157 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/g0rbe/vps-sentinel/report"
+)
+
+// diffIssues returns the issues of the new report that are not in the old one, with + prefix,
+// and the issues of the old report that are resolved in the new one, with - prefix
+func diffIssues(older, newer report.Report) []string {
+
+	lines := make([]string, 0)
+
+	issueSet := func(r report.Report) map[string]bool {
+
+		set := make(map[string]bool)
+
+		for _, issue := range r.Issues() {
+			set[issueLine(issue)] = true
+		}
+
+		return set
+	}
+
+	oldIssues, newIssues := issueSet(older), issueSet(newer)
+
+	for _, issue := range newer.Issues() {
+		if !oldIssues[issueLine(issue)] {
+			lines = append(lines, "+ "+issueLine(issue))
+		}
+	}
+
+	for _, issue := range older.Issues() {
+		if !newIssues[issueLine(issue)] {
+			lines = append(lines, "- "+issueLine(issue))
+		}
+	}
+
+	return lines
+}
+
+// issueLine formats the issue as "[SEVERITY] Section: message"
+func issueLine(issue report.Issue) string {
+	return fmt.Sprintf("[%s] %s: %s", strings.ToUpper(issue.Severity.String()), issue.Section, issue.Message)
+}
+
+// diffSection returns the differences of the same section in two reports
+func diffSection(older, newer report.Section, since time.Time) []string {
+
+	lines := make([]string, 0)
+
+	if older.Status != newer.Status {
+		lines = append(lines, fmt.Sprintf("~ Status: %s -> %s", older.Status, newer.Status))
+	}
+
+	if older.Status != report.StatusOK || newer.Status != report.StatusOK {
+		return lines
+	}
+
+	oldFields := make(map[string]report.Field)
+
+	for _, field := range older.Fields {
+		oldFields[field.Key] = field
+	}
+
+	for _, field := range newer.Fields {
+
+		prev, ok := oldFields[field.Key]
+
+		if !ok {
+			lines = append(lines, fmt.Sprintf("+ %s: %s", field.Label, FormatValue(field.Value, field.Unit)))
+			continue
+		}
+
+		before, after := FormatValue(prev.Value, prev.Unit), FormatValue(field.Value, field.Unit)
+
+		if before != after {
+			lines = append(lines, fmt.Sprintf("~ %s: %s -> %s", field.Label, before, after))
+		}
+	}
+
+	if newer.Table != nil && older.Table != nil {
+
+		// Tables without key are compared by their whole rows
+		if len(newer.Table.Key) == 0 {
+
+			table := *newer.Table
+			newer.Table = &table
+
+			for _, column := range table.Columns {
+				newer.Table.Key = append(newer.Table.Key, column.Name)
+			}
+		}
+
+		newer.Diff(older, since)
+
+		if newer.Changes != nil {
+
+			for _, added := range newer.Changes.Added {
+				lines = append(lines, "+ "+added)
+			}
+
+			for _, removed := range newer.Changes.Removed {
+				lines = append(lines, "- "+removed)
+			}
+		}
+	}
+
+	return lines
+}
+
+// Diff renders the differences between two reports as plain text:
+// the new and resolved issues, then the added, removed and changed sections, fields and rows
+func Diff(older, newer report.Report) string {
+
+	text := fmt.Sprintf("Changes from %s to %s\n\n",
+		older.Time.Format("2006-01-02 15:04:05"), newer.Time.Format("2006-01-02 15:04:05"))
+
+	if lines := diffIssues(older, newer); len(lines) > 0 {
+		text += banner("Issues") + strings.Join(lines, "\n") + "\n\n"
+	}
+
+	oldSections := make(map[string]report.Section)
+
+	for _, s := range older.Sections {
+		oldSections[s.ID] = s
+	}
+
+	newSections := make(map[string]bool)
+
+	for _, s := range newer.Sections {
+
+		newSections[s.ID] = true
+
+		prev, ok := oldSections[s.ID]
+
+		if !ok {
+			text += banner(s.Title) + "+ New section\n\n"
+			continue
+		}
+
+		if lines := diffSection(prev, s, older.Time); len(lines) > 0 {
+			text += banner(s.Title) + strings.Join(lines, "\n") + "\n\n"
+		}
+	}
+
+	for _, s := range older.Sections {
+		if !newSections[s.ID] {
+			text += banner(s.Title) + "- Removed section\n\n"
+		}
+	}
+
+	return text
+}
@@ -0,0 +1,94 @@
+package render
+
+import (
+	"html/template"
+
+	"github.com/g0rbe/vps-sentinel/report"
+)
+
+// severityColors are the background colors of the findings' severity
+var severityColors = map[report.Severity]string{
+	report.Info:     "#d9edf7",
+	report.Warning:  "#fcf8e3",
+	report.Critical: "#f2dede",
+}
+
+var htmlFuncs = template.FuncMap{
+	"format":  FormatValue,
+	"summary": Summary,
+	"subject": Subject,
+	"upper":   upper,
+	"color": func(s report.Severity) string {
+		return severityColors[s]
+	},
+}
+
+// htmlLayout is the built-in HTML template, "section" renders one section
+const htmlLayout = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Report of {{.Host}}</title>
+</head>
+<body style="font-family: sans-serif; font-size: 14px; color: #333333;">
+<h1 style="font-size: 20px;">Report of {{.Host}}</h1>
+<p style="color: #777777;">{{.Time.Format "2006-01-02 15:04:05 MST"}}</p>
+<h2 style="font-size: 16px; border-bottom: 1px solid #cccccc;">Summary</h2>
+<p style="font-weight: bold;">{{summary .}}</p>
+{{- with .Issues}}
+<table cellpadding="4" style="border-collapse: collapse; margin-bottom: 8px;">
+{{- range .}}
+<tr><td style="background-color: {{color .Severity}}; font-weight: bold;">{{.Severity}}</td><td>{{.Section}}</td><td>{{.Message}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{range .Sections}}{{template "section" .}}{{end}}
+</body>
+</html>
+{{define "section"}}
+<h2 style="font-size: 16px; border-bottom: 1px solid #cccccc;">{{.Title}}</h2>
+{{- if ne .Status "ok"}}
+<p style="background-color: #f2dede; padding: 4px;">Failed to get {{.Title}}: {{.Error}}</p>
+{{- else}}
+{{- if .Findings}}
+<table cellpadding="4" style="border-collapse: collapse; margin-bottom: 8px;">
+{{- range .Findings}}
+<tr><td style="background-color: {{color .Severity}}; font-weight: bold;">{{.Severity}}</td><td>{{.Message}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- with .Changes}}
+{{- if .Empty}}
+<p style="color: #777777;">No changes since last run ({{.Since.Format "2006-01-02 15:04"}})</p>
+{{- else}}
+<p style="margin-bottom: 2px;">Changes since last run ({{.Since.Format "2006-01-02 15:04"}}):</p>
+<table cellpadding="4" style="border-collapse: collapse; margin-bottom: 8px;">
+{{- range .Added}}
+<tr><td style="background-color: #dff0d8; font-weight: bold;">+</td><td>{{.}}</td></tr>
+{{- end}}
+{{- range .Removed}}
+<tr><td style="background-color: #f2dede; font-weight: bold;">-</td><td>{{.}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- end}}
+{{- if .Fields}}
+<table cellpadding="4" style="border-collapse: collapse; margin-bottom: 8px;">
+{{- range .Fields}}
+<tr><td style="font-weight: bold;">{{.Label}}</td><td>{{format .Value .Unit}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- with .Table}}
+<table border="1" cellpadding="4" style="border-collapse: collapse; border-color: #cccccc; margin-bottom: 8px;">
+<tr>{{range .Columns}}<th style="background-color: #eeeeee; text-align: left;">{{.Name}}</th>{{end}}</tr>
+{{- range .Rows}}
+<tr>{{range .}}<td style="word-break: break-all;">{{format . ""}}</td>{{end}}</tr>
+{{- end}}
+</table>
+{{- end}}
+{{- if .Text}}
+<pre>{{.Text}}</pre>
+{{- end}}
+{{- end}}
+{{end}}`
@@ -0,0 +1,46 @@
+package render
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/g0rbe/vps-sentinel/report"
+)
+
+// SchemaVersion is the version of the JSON report's schema.
+// Increment it on every incompatible change in the report package.
+const SchemaVersion = 1
+
+// jsonReport is the top level object of the JSON report
+type jsonReport struct {
+	SchemaVersion int `json:"schema_version"`
+	report.Report
+}
+
+// JSON renders the report as indented JSON
+func JSON(r report.Report) ([]byte, error) {
+
+	return json.MarshalIndent(jsonReport{SchemaVersion: SchemaVersion, Report: r}, "", "  ")
+}
+
+// jsonSummary is the top level object of the JSON summary
+type jsonSummary struct {
+	SchemaVersion int             `json:"schema_version"`
+	Host          string          `json:"host"`
+	Time          time.Time       `json:"time"`
+	Severity      report.Severity `json:"severity"`
+	Summary       string          `json:"summary"`
+	Issues        []report.Issue  `json:"issues"`
+}
+
+// JSONSummary renders the summary and the issues of the report as JSON
+func JSONSummary(r report.Report) ([]byte, error) {
+
+	return json.MarshalIndent(jsonSummary{
+		SchemaVersion: SchemaVersion,
+		Host:          r.Host,
+		Time:          r.Time,
+		Severity:      r.Severity(),
+		Summary:       Summary(r),
+		Issues:        r.Issues()}, "", "  ")
+}
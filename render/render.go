@@ -0,0 +1,108 @@
+// Package render turns the structured report into a readable format
+package render
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/table"
+
+	"github.com/g0rbe/vps-sentinel/report"
+)
+
+// FormatValue returns the human readable form of a value with the given unit
+func FormatValue(value interface{}, unit string) string {
+
+	switch v := value.(type) {
+	case float64:
+		switch unit {
+		case report.Bytes:
+			return fmt.Sprintf("%.2f MiB", v/1048576.0)
+		case report.Seconds:
+			return fmt.Sprintf("%.3f day(s)", v/86400.0)
+		case "":
+			return fmt.Sprintf("%.2f", v)
+		default:
+			return fmt.Sprintf("%.2f %s", v, unit)
+		}
+	case nil:
+		return ""
+	}
+
+	if unit == "" {
+		return fmt.Sprint(value)
+	}
+
+	return fmt.Sprintf("%v %s", value, unit)
+}
+
+// banner returns the header of a section
+func banner(title string) string {
+
+	hashes := strings.Repeat("#", 14)
+
+	return hashes + " " + title + " " + hashes + "\n\n"
+}
+
+// textTable renders the table with go-pretty
+func textTable(t *report.Table) string {
+
+	w := table.NewWriter()
+
+	header := make(table.Row, 0, len(t.Columns))
+
+	for _, column := range t.Columns {
+		header = append(header, column.Name)
+	}
+
+	w.AppendHeader(header)
+
+	for _, row := range t.Rows {
+
+		cells := make(table.Row, 0, len(row))
+
+		for _, value := range row {
+			cells = append(cells, FormatValue(value, ""))
+		}
+
+		w.AppendRow(cells)
+	}
+
+	return w.Render() + "\n\n"
+}
+
+// Summary returns the one line summary of the issues, eg.: "CRITICAL: 2 issues"
+func Summary(r report.Report) string {
+
+	issues := r.Issues()
+
+	switch len(issues) {
+	case 0:
+		return "No issues found"
+	case 1:
+		return strings.ToUpper(r.Severity().String()) + ": 1 issue"
+	}
+
+	return fmt.Sprintf("%s: %d issues", strings.ToUpper(r.Severity().String()), len(issues))
+}
+
+// Subject returns the subject of the report's mail
+func Subject(r report.Report) string {
+
+	if len(r.Issues()) == 0 {
+
+		if r.Kind == report.KindCheck {
+			return fmt.Sprintf("[%s] Changes detected by vps-sentinel", r.Host)
+		}
+
+		return fmt.Sprintf("[%s] Daily report from vps-sentinel", r.Host)
+	}
+
+	if r.Kind == report.KindAlert {
+		issue := r.Issues()[0]
+		return fmt.Sprintf("[%s] %s: %s", r.Host, strings.ToUpper(issue.Severity.String()),
+			issue.Message)
+	}
+
+	return fmt.Sprintf("[%s] %s", r.Host, Summary(r))
+}
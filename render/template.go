@@ -0,0 +1,166 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"io/ioutil"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/g0rbe/vps-sentinel/report"
+)
+
+// textLayout is the built-in plain text template.
+// The "subject" template is the subject of the mail, "section" renders one section.
+const textLayout = `{{define "subject"}}{{subject .}}{{end -}}
+{{banner "Summary"}}{{summary .}}
+{{range .Issues}}[{{upper .Severity}}] {{.Section}}: {{.Message}}
+{{end}}
+{{range .Sections}}{{template "section" .}}{{end}}
+{{- define "section"}}{{banner .Title}}
+{{- if ne .Status "ok"}}Failed to get {{.Title}}: {{.Error}}
+
+{{else}}
+{{- if .Findings}}
+{{- range .Findings}}[{{upper .Severity}}] {{.Message}}
+{{end}}
+{{end}}
+{{- with .Changes}}
+{{- if .Empty}}No changes since last run ({{.Since.Format "2006-01-02 15:04"}})
+{{else}}Changes since last run ({{.Since.Format "2006-01-02 15:04"}}):
+{{end}}
+{{- range .Added}}+ {{.}}
+{{end}}
+{{- range .Removed}}- {{.}}
+{{end}}
+{{end}}
+{{- if .Fields}}
+{{- range .Fields}}- {{.Label}}: {{format .Value .Unit}}
+{{end}}
+{{end}}
+{{- with .Table}}{{table .}}{{end}}
+{{- if .Text}}{{.Text}}
+
+{{end}}
+{{- end}}
+{{- end}}`
+
+// upper returns the upper case string form of the value, eg.: CRITICAL for a severity
+func upper(value interface{}) string {
+	return strings.ToUpper(fmt.Sprint(value))
+}
+
+// textFuncs are the functions of the text templates
+var textFuncs = texttemplate.FuncMap{
+	"banner":  banner,
+	"table":   textTable,
+	"format":  FormatValue,
+	"summary": Summary,
+	"subject": Subject,
+	"upper":   upper,
+}
+
+// Templates renders the report with the built-in templates, or the user's templates which
+// are parsed over the built-in ones. So a template file may redefine only the "subject"
+// or the "section" template, and keep the rest of the built-in layout.
+type Templates struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+// builtin returns the built-in templates.
+// Every call parses them again, a html/template can not be cloned after it is executed.
+func builtin() *Templates {
+	return &Templates{
+		text: texttemplate.Must(texttemplate.New("report").Funcs(textFuncs).Parse(textLayout)),
+		html: htmltemplate.Must(htmltemplate.New("report").Funcs(htmlFuncs).Parse(htmlLayout))}
+}
+
+// defaultTemplates are the built-in templates
+var defaultTemplates = builtin()
+
+// LoadTemplates parses the text/template in textPath and the html/template in htmlPath.
+// The built-in template is used if the path is empty.
+func LoadTemplates(textPath, htmlPath string) (*Templates, error) {
+
+	t := builtin()
+
+	if textPath != "" {
+
+		content, err := ioutil.ReadFile(textPath)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %s", textPath, err)
+		}
+
+		if t.text, err = t.text.Parse(string(content)); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %s", textPath, err)
+		}
+	}
+
+	if htmlPath != "" {
+
+		content, err := ioutil.ReadFile(htmlPath)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %s", htmlPath, err)
+		}
+
+		if t.html, err = t.html.Parse(string(content)); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %s", htmlPath, err)
+		}
+	}
+
+	return t, nil
+}
+
+// Text renders the report as plain text
+func (t *Templates) Text(r report.Report) (string, error) {
+
+	var buf bytes.Buffer
+
+	if err := t.text.Execute(&buf, r); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// HTML renders the report as a HTML document
+func (t *Templates) HTML(r report.Report) (string, error) {
+
+	var buf bytes.Buffer
+
+	if err := t.html.Execute(&buf, r); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// Subject renders the "subject" template of the text template, the lines are joined into one
+func (t *Templates) Subject(r report.Report) (string, error) {
+
+	var buf bytes.Buffer
+
+	if err := t.text.ExecuteTemplate(&buf, "subject", r); err != nil {
+		return "", err
+	}
+
+	return strings.Join(strings.Fields(buf.String()), " "), nil
+}
+
+// Text renders the report as plain text with the built-in template
+func Text(r report.Report) string {
+
+	// The built-in template does not fail on any report
+	text, _ := defaultTemplates.Text(r)
+
+	return text
+}
+
+// HTML renders the report as a HTML document with the built-in template
+func HTML(r report.Report) (string, error) {
+	return defaultTemplates.HTML(r)
+}
@@ -0,0 +1,103 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Changes lists the rows of a table added or removed since the previous run
+type Changes struct {
+	Since   time.Time `json:"since"` // Time of the previous run
+	Added   []string  `json:"added"`
+	Removed []string  `json:"removed"`
+}
+
+// Empty reports whether nothing changed
+func (c *Changes) Empty() bool {
+	return len(c.Added) == 0 && len(c.Removed) == 0
+}
+
+// rowKeys returns the identity of every row, built from the key columns.
+// Returns nil if the table has no key.
+func (t *Table) rowKeys() []string {
+
+	if len(t.Key) == 0 {
+		return nil
+	}
+
+	indexes := make([]int, 0, len(t.Key))
+
+	for _, key := range t.Key {
+		if i := t.Column(key); i != -1 {
+			indexes = append(indexes, i)
+		}
+	}
+
+	keys := make([]string, 0, len(t.Rows))
+	seen := make(map[string]bool)
+
+	for _, row := range t.Rows {
+
+		parts := make([]string, 0, len(indexes))
+
+		for _, i := range indexes {
+			if i < len(row) {
+				parts = append(parts, fmt.Sprintf("%s: %v", t.Columns[i].Name, row[i]))
+			}
+		}
+
+		key := strings.Join(parts, ", ")
+
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+// Diff compares the table of the section with the previous run's one and sets s.Changes.
+// Nothing happens if any of the sections failed or the table has no key.
+func (s *Section) Diff(prev Section, since time.Time) {
+
+	if s.Status != StatusOK || prev.Status != StatusOK || s.Table == nil || prev.Table == nil {
+		return
+	}
+
+	curKeys := s.Table.rowKeys()
+
+	if curKeys == nil {
+		return
+	}
+
+	// Key of the previous run is ignored, the current one tells the identity
+	prevTable := *prev.Table
+	prevTable.Key = s.Table.Key
+	prevKeys := prevTable.rowKeys()
+
+	changes := &Changes{Since: since, Added: make([]string, 0), Removed: make([]string, 0)}
+
+	prevSet := make(map[string]bool)
+	for _, key := range prevKeys {
+		prevSet[key] = true
+	}
+
+	curSet := make(map[string]bool)
+	for _, key := range curKeys {
+		curSet[key] = true
+
+		if !prevSet[key] {
+			changes.Added = append(changes.Added, key)
+		}
+	}
+
+	for _, key := range prevKeys {
+		if !curSet[key] {
+			changes.Removed = append(changes.Removed, key)
+		}
+	}
+
+	s.Changes = changes
+}
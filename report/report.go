@@ -0,0 +1,227 @@
+// Package report defines the structured model of the report.
+// Collectors fill the model, renderers turn it into text, html, etc.
+package report
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Severity is the importance of a finding
+type Severity int
+
+const (
+	// Info is a finding which needs no action
+	Info Severity = iota
+	// Warning is a finding which should be checked
+	Warning
+	// Critical is a finding which needs action
+	Critical
+)
+
+var severityNames = []string{"info", "warning", "critical"}
+
+func (s Severity) String() string {
+
+	if s < Info || s > Critical {
+		return fmt.Sprintf("severity(%d)", int(s))
+	}
+
+	return severityNames[s]
+}
+
+// MarshalText encodes the severity as its name
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText decodes the name of a severity
+func (s *Severity) UnmarshalText(text []byte) error {
+
+	for i, name := range severityNames {
+		if strings.EqualFold(name, string(text)) {
+			*s = Severity(i)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("invalid severity: %s", text)
+}
+
+// Status is the outcome of collecting a section
+type Status string
+
+const (
+	// StatusOK means the section is collected successfully
+	StatusOK Status = "ok"
+	// StatusError means that collecting the section failed, see Section.Error
+	StatusError Status = "error"
+	// StatusTimeout means that the collector did not finish in time, see Section.Error
+	StatusTimeout Status = "timeout"
+)
+
+// ColumnType is the type of the values in a table column
+type ColumnType string
+
+const (
+	// String column holds string values
+	String ColumnType = "string"
+	// Int column holds int values
+	Int ColumnType = "int"
+	// Float column holds float64 values
+	Float ColumnType = "float"
+)
+
+// Column describes one column of a table
+type Column struct {
+	Name string     `json:"name"`
+	Type ColumnType `json:"type"`
+}
+
+// Row is one row of a table, the values are in the order of the columns
+type Row []interface{}
+
+// Table is a list of rows with typed columns
+type Table struct {
+	Columns []Column `json:"columns"`
+	Rows    []Row    `json:"rows"`
+	Key     []string `json:"key,omitempty"` // Columns that identify a row between runs
+}
+
+// NewTable creates an empty table with the given columns
+func NewTable(columns ...Column) *Table {
+	return &Table{Columns: columns, Rows: make([]Row, 0)}
+}
+
+// Append adds a row to the table
+func (t *Table) Append(values ...interface{}) {
+	t.Rows = append(t.Rows, Row(values))
+}
+
+// Column returns the index of the named column, -1 if not found
+func (t *Table) Column(name string) int {
+
+	for i, c := range t.Columns {
+		if c.Name == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// Units of the fields, that renderers display in a human readable form
+const (
+	Bytes   = "bytes"
+	Seconds = "seconds"
+)
+
+// Field is a single named value, eg.: the system load
+type Field struct {
+	Key   string      `json:"key"`   // Machine readable name
+	Label string      `json:"label"` // Human readable name
+	Value interface{} `json:"value"`
+	Unit  string      `json:"unit,omitempty"`
+}
+
+// Finding is something in a section that worth the attention of the reader
+type Finding struct {
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+}
+
+// Section is one part of the report, printed under its own title
+type Section struct {
+	ID        string    `json:"id"`        // Unique identifier of the section, eg.: port.tcp
+	Collector string    `json:"collector"` // Name of the collector in report->structure
+	Title     string    `json:"title"`
+	Status    Status    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	Fields    []Field   `json:"fields,omitempty"`
+	Table     *Table    `json:"table,omitempty"`
+	Text      string    `json:"text,omitempty"`
+	Findings  []Finding `json:"findings,omitempty"`
+	Changes   *Changes  `json:"changes,omitempty"` // Changes since the previous run
+}
+
+// SetError marks the section as failed
+func (s *Section) SetError(err error) {
+	s.Status = StatusError
+	s.Error = err.Error()
+}
+
+// Kinds of the report
+const (
+	// KindReport is the full, scheduled report
+	KindReport = "report"
+	// KindCheck is the result of the frequent checks in daemon mode
+	KindCheck = "check"
+	// KindAlert is an immediate alert, eg.: an SSH login
+	KindAlert = "alert"
+)
+
+// Report is the whole report of one run
+type Report struct {
+	Kind     string    `json:"kind"`
+	Host     string    `json:"host"`
+	Time     time.Time `json:"time"`
+	Sections []Section `json:"sections"`
+}
+
+// Issue is a finding with warning or higher severity, and the title of its section
+type Issue struct {
+	Section string `json:"section"`
+	Finding
+}
+
+// Issues returns the findings with warning or higher severity from every section
+func (r Report) Issues() []Issue {
+
+	issues := make([]Issue, 0)
+
+	for _, section := range r.Sections {
+		for _, finding := range section.Findings {
+			if finding.Severity >= Warning {
+				issues = append(issues, Issue{Section: section.Title, Finding: finding})
+			}
+		}
+	}
+
+	return issues
+}
+
+// Severity returns the highest severity of the findings in the report
+func (r Report) Severity() Severity {
+
+	severity := Info
+
+	for _, section := range r.Sections {
+		for _, finding := range section.Findings {
+			if finding.Severity > severity {
+				severity = finding.Severity
+			}
+		}
+	}
+
+	return severity
+}
+
+// Only returns a copy of the report with the sections of the given collectors
+func (r Report) Only(collectors []string) Report {
+
+	sections := make([]Section, 0)
+
+	for _, section := range r.Sections {
+		for _, collector := range collectors {
+			if section.Collector == collector {
+				sections = append(sections, section)
+				break
+			}
+		}
+	}
+
+	r.Sections = sections
+
+	return r
+}
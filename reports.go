@@ -0,0 +1,168 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/g0rbe/vps-sentinel/render"
+)
+
+// listCmd prints the archived reports with the summary of their issues
+func listCmd(args []string) int {
+
+	var opts options
+
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	opts.register(fs)
+	fs.Parse(args)
+
+	conf, err := opts.loadConfig()
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to parse configuration file: %s\n", err)
+		return 1
+	}
+
+	a := reportArchive(conf)
+
+	ids, err := a.List()
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to list reports: %s\n", err)
+		return 1
+	}
+
+	if len(ids) == 0 {
+		fmt.Printf("No reports in %s\n", a.Dir)
+		return 0
+	}
+
+	for _, id := range ids {
+
+		r, err := a.Load(id)
+
+		if err != nil {
+			fmt.Printf("%s  %s\n", id, err)
+			continue
+		}
+
+		fmt.Printf("%s  %s\n", id, render.Summary(r))
+	}
+
+	return 0
+}
+
+// showCmd prints an archived report
+func showCmd(args []string) int {
+
+	var opts options
+
+	fs := flag.NewFlagSet("show", flag.ExitOnError)
+	opts.register(fs)
+	format := fs.String("format", "text", "format of the report: text or json")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: vps-sentinel show [flags] <date|latest>\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 || *format != "text" && *format != "json" {
+		fs.Usage()
+		return 2
+	}
+
+	conf, err := opts.loadConfig()
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to parse configuration file: %s\n", err)
+		return 1
+	}
+
+	a := reportArchive(conf)
+
+	id, err := a.Find(fs.Arg(0))
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to find report: %s\n", err)
+		return 1
+	}
+
+	switch *format {
+	case "text":
+		text, err := a.Text(id)
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to read report: %s\n", err)
+			return 1
+		}
+
+		fmt.Print(text)
+	case "json":
+		content, err := a.JSON(id)
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to read report: %s\n", err)
+			return 1
+		}
+
+		fmt.Printf("%s\n", content)
+	}
+
+	return 0
+}
+
+// diffCmd prints the changes between two archived reports
+func diffCmd(args []string) int {
+
+	var opts options
+
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	opts.register(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: vps-sentinel diff [flags] <date1> <date2|latest>\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return 2
+	}
+
+	conf, err := opts.loadConfig()
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to parse configuration file: %s\n", err)
+		return 1
+	}
+
+	a := reportArchive(conf)
+
+	ids := make([]string, 2)
+
+	for i, date := range fs.Args() {
+		if ids[i], err = a.Find(date); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to find report: %s\n", err)
+			return 1
+		}
+	}
+
+	older, err := a.Load(ids[0])
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read report: %s\n", err)
+		return 1
+	}
+
+	newer, err := a.Load(ids[1])
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read report: %s\n", err)
+		return 1
+	}
+
+	fmt.Print(render.Diff(older, newer))
+
+	return 0
+}
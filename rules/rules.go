@@ -0,0 +1,281 @@
+// Package rules classifies the content of the report by configurable thresholds
+package rules
+
+import (
+	"fmt"
+
+	"github.com/g0rbe/vps-sentinel/configparser"
+	"github.com/g0rbe/vps-sentinel/report"
+)
+
+// rule checks a section and returns its findings
+type rule func(s report.Section, conf configparser.Config) []report.Finding
+
+// rules are the rules by the ID of the section they check
+var rules = map[string][]rule{
+	"system":           {memoryRule, loadRule},
+	"system:disk":      {diskRule},
+	"system:cpu":       {cpuRule},
+	"log.ssh:failed":   {sshFailedRule},
+	"log.nginx:server": {nginx5xxRule},
+}
+
+// rulesByCollector are the rules for every section of a collector
+var rulesByCollector = map[string][]rule{
+	"clamav": {clamavRule},
+}
+
+// check returns the severity of the value, false if it is below the warning threshold
+func check(t configparser.Threshold, value float64) (report.Severity, bool) {
+
+	switch {
+	case value >= t.Critical:
+		return report.Critical, true
+	case value >= t.Warning:
+		return report.Warning, true
+	}
+
+	return report.Info, false
+}
+
+// field returns the numeric value of the field with the given key
+func field(s report.Section, key string) (float64, bool) {
+
+	for _, f := range s.Fields {
+		if f.Key == key {
+			v, ok := f.Value.(float64)
+			return v, ok
+		}
+	}
+
+	return 0, false
+}
+
+// memoryRule checks the used memory in percent.
+// The memory available without swapping is not used, the page cache is not counted.
+func memoryRule(s report.Section, conf configparser.Config) []report.Finding {
+
+	threshold, enabled := conf.Thresholds["memory"]
+
+	total, okTotal := field(s, "mem_total")
+	available, okAvailable := field(s, "mem_available")
+
+	if !enabled || !okTotal || !okAvailable || total == 0 {
+		return nil
+	}
+
+	used := 100 * (total - available) / total
+
+	if severity, ok := check(threshold, used); ok {
+		return []report.Finding{{Severity: severity,
+			Message: fmt.Sprintf("Memory usage is %.1f%%", used)}}
+	}
+
+	return nil
+}
+
+// loadRule checks the 5 minutes average load per online CPU
+func loadRule(s report.Section, conf configparser.Config) []report.Finding {
+
+	threshold, enabled := conf.Thresholds["load"]
+
+	perCPU, ok := field(s, "load5_per_cpu")
+
+	if !enabled || !ok {
+		return nil
+	}
+
+	if severity, ok := check(threshold, perCPU); ok {
+		return []report.Finding{{Severity: severity,
+			Message: fmt.Sprintf("Average load (5 min) per CPU is %.2f", perCPU)}}
+	}
+
+	return nil
+}
+
+// cpuRule checks the time stolen by the hypervisor and spent waiting for I/O of all CPUs in percent
+func cpuRule(s report.Section, conf configparser.Config) []report.Finding {
+
+	if s.Table == nil {
+		return nil
+	}
+
+	cpuCol := s.Table.Column("CPU")
+
+	if cpuCol == -1 {
+		return nil
+	}
+
+	findings := make([]report.Finding, 0)
+
+	for _, limit := range []struct{ rule, column, name string }{
+		{"cpu_steal", "Steal (%)", "Steal time"},
+		{"cpu_iowait", "IOwait (%)", "I/O wait"},
+	} {
+
+		threshold, enabled := conf.Thresholds[limit.rule]
+		col := s.Table.Column(limit.column)
+
+		if !enabled || col == -1 {
+			continue
+		}
+
+		for _, row := range s.Table.Rows {
+
+			value, ok := row[col].(float64)
+
+			if !ok || row[cpuCol] != "all" {
+				continue
+			}
+
+			if severity, ok := check(threshold, value); ok {
+				findings = append(findings, report.Finding{Severity: severity,
+					Message: fmt.Sprintf("%s of the CPUs is %.1f%%", limit.name, value)})
+			}
+		}
+	}
+
+	return findings
+}
+
+// diskRule checks the used space and inodes of every filesystem in percent
+func diskRule(s report.Section, conf configparser.Config) []report.Finding {
+
+	if s.Table == nil {
+		return nil
+	}
+
+	mountCol := s.Table.Column("Mountpoint")
+
+	if mountCol == -1 {
+		return nil
+	}
+
+	findings := make([]report.Finding, 0)
+
+	for _, limit := range []struct{ rule, column, name string }{
+		{"disk", "Used (%)", "space"},
+		{"inodes", "Inodes used (%)", "inodes"},
+	} {
+
+		threshold, enabled := conf.Thresholds[limit.rule]
+		col := s.Table.Column(limit.column)
+
+		if !enabled || col == -1 {
+			continue
+		}
+
+		for _, row := range s.Table.Rows {
+
+			used, ok := row[col].(float64)
+
+			if !ok {
+				continue
+			}
+
+			if severity, ok := check(threshold, used); ok {
+				findings = append(findings, report.Finding{Severity: severity,
+					Message: fmt.Sprintf("Used %s of %v is %.1f%%", limit.name, row[mountCol], used)})
+			}
+		}
+	}
+
+	return findings
+}
+
+// sshFailedRule checks the number of failed logins per IP
+func sshFailedRule(s report.Section, conf configparser.Config) []report.Finding {
+
+	threshold, enabled := conf.Thresholds["ssh_failed"]
+
+	if !enabled || s.Table == nil {
+		return nil
+	}
+
+	ipCol, countCol := s.Table.Column("IP"), s.Table.Column("Count")
+
+	if ipCol == -1 || countCol == -1 {
+		return nil
+	}
+
+	findings := make([]report.Finding, 0)
+
+	for _, row := range s.Table.Rows {
+
+		count, ok := row[countCol].(int)
+
+		if !ok {
+			continue
+		}
+
+		if severity, ok := check(threshold, float64(count)); ok {
+			findings = append(findings, report.Finding{Severity: severity,
+				Message: fmt.Sprintf("%d failed SSH logins from %v", count, row[ipCol])})
+		}
+	}
+
+	return findings
+}
+
+// nginx5xxRule checks the number of server errors
+func nginx5xxRule(s report.Section, conf configparser.Config) []report.Finding {
+
+	threshold, enabled := conf.Thresholds["nginx_5xx"]
+
+	if !enabled || s.Table == nil {
+		return nil
+	}
+
+	count := len(s.Table.Rows)
+
+	if severity, ok := check(threshold, float64(count)); ok {
+		return []report.Finding{{Severity: severity,
+			Message: fmt.Sprintf("%d server errors in Nginx's log", count)}}
+	}
+
+	return nil
+}
+
+// clamavRule reports every file found by ClamAV with the severity in rules->clamav
+func clamavRule(s report.Section, conf configparser.Config) []report.Finding {
+
+	if s.Table == nil {
+		return nil
+	}
+
+	fileCol, sigCol := s.Table.Column("File"), s.Table.Column("Signature")
+
+	if fileCol == -1 || sigCol == -1 {
+		return nil
+	}
+
+	findings := make([]report.Finding, 0, len(s.Table.Rows))
+
+	for _, row := range s.Table.Rows {
+		findings = append(findings, report.Finding{Severity: conf.ClamAVSeverity,
+			Message: fmt.Sprintf("%v found in %v", row[sigCol], row[fileCol])})
+	}
+
+	return findings
+}
+
+// Evaluate runs the rules on the sections of the report and appends the findings
+func Evaluate(r *report.Report, conf configparser.Config) {
+
+	for i := range r.Sections {
+
+		section := &r.Sections[i]
+
+		if section.Status != report.StatusOK {
+			continue
+		}
+
+		checks := make([]rule, 0)
+		checks = append(checks, rules[section.ID]...)
+		checks = append(checks, rulesByCollector[section.Collector]...)
+
+		for _, check := range checks {
+			section.Findings = append(section.Findings, check(*section, conf)...)
+		}
+	}
+}
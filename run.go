@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/g0rbe/vps-sentinel/configparser"
+	"github.com/g0rbe/vps-sentinel/internal/atomicfile"
+	"github.com/g0rbe/vps-sentinel/notify"
+	"github.com/g0rbe/vps-sentinel/render"
+	"github.com/g0rbe/vps-sentinel/report"
+)
+
+// runReport collects the report, writes the JSON outputs and sends the report with the notifiers.
+// The report is returned even if sending failed.
+// The progress messages are written to progress.
+func runReport(ctx context.Context, conf configparser.Config, progress io.Writer) (report.Report, error) {
+
+	flushSpool(ctx, conf, progress)
+
+	r := collectReport(ctx, conf, progress)
+
+	// Canceled sections, eg.: by SIGTERM, are not worth keeping or sending
+	if ctx.Err() != nil {
+		return r, fmt.Errorf("report canceled: %s", ctx.Err())
+	}
+
+	if err := snapshotStore(conf).Save(r); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to save state: %s\n", err)
+	}
+
+	// Not saved in the snapshots, the note is sent only once
+	if section, ok := lateDeliveries(conf); ok {
+		r.Sections = append([]report.Section{section}, r.Sections...)
+	}
+
+	if conf.ArchiveEnabled {
+		if _, err := reportArchive(conf).Save(r); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to archive report: %s\n", err)
+		}
+	}
+
+	if len(conf.ReportJSON) > 0 {
+
+		jsonReport, err := render.JSON(r)
+
+		if err != nil {
+			return r, fmt.Errorf("failed to render JSON report: %s", err)
+		}
+
+		if configparser.Contains(conf.ReportJSON, "stdout") {
+			fmt.Printf("%s\n", jsonReport)
+		}
+
+		if configparser.Contains(conf.ReportJSON, "file") {
+
+			fmt.Fprintf(progress, "Writing JSON report to %s...\n", conf.ReportJSONFile)
+
+			// Readers polling the file never see a half written report
+			if err := atomicfile.Write(conf.ReportJSONFile, jsonReport, 0600); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to write JSON report: %s\n", err)
+			}
+		}
+	}
+
+	return r, sendReport(ctx, conf, r, progress)
+}
+
+// flushSpool retries the spooled mails which are due, if smtp is in report->notify
+func flushSpool(ctx context.Context, conf configparser.Config, progress io.Writer) {
+
+	if !configparser.Contains(conf.Notifiers, "smtp") {
+		return
+	}
+
+	delivered, err := notify.NewSMTP(conf).Flush(ctx, false)
+
+	if delivered > 0 {
+		fmt.Fprintf(progress, "Delivered %d spooled mail(s)\n", delivered)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to flush mail spool: %s\n", err)
+	}
+}
+
+// lateDeliveries returns a section of the spooled mails which are delivered since the previous report.
+// Returns false if there is none.
+func lateDeliveries(conf configparser.Config) (report.Section, bool) {
+
+	section := report.Section{
+		ID:        "spool",
+		Collector: "spool",
+		Title:     "Late deliveries",
+		Status:    report.StatusOK}
+
+	late, err := notify.NewSMTP(conf).Spool().TakeLate()
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read late deliveries: %s\n", err)
+		return section, false
+	}
+
+	if len(late) == 0 {
+		return section, false
+	}
+
+	section.Findings = []report.Finding{{
+		Severity: report.Info,
+		Message:  fmt.Sprintf("%d earlier report(s) failed to send at first and were delivered late", len(late))}}
+	section.Table = report.NewTable(
+		report.Column{Name: "Subject", Type: report.String},
+		report.Column{Name: "Queued", Type: report.String},
+		report.Column{Name: "Delivered", Type: report.String},
+		report.Column{Name: "Attempts", Type: report.Int})
+
+	for _, m := range late {
+		section.Table.Append(m.Subject, m.Queued.Format(time.RFC1123), m.Delivered.Format(time.RFC1123), m.Attempts)
+	}
+
+	return section, true
+}
+
+// sendReport sends the report with every notifier in report->notify
+func sendReport(ctx context.Context, conf configparser.Config, r report.Report, progress io.Writer) error {
+
+	failed := 0
+
+	for _, n := range notify.FromConfig(conf) {
+
+		fmt.Fprintf(progress, "Sending report with %s...\n", n.Name())
+
+		if err := n.Notify(ctx, r); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to send report with %s: %s\n", n.Name(), err)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d notifier(s) failed", failed)
+	}
+
+	return nil
+}
+
+// runCmd collects the report, writes the JSON outputs and sends the report with the notifiers
+func runCmd(args []string) int {
+
+	var opts options
+
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	opts.register(fs)
+	fs.Parse(args)
+
+	conf, err := opts.loadConfig()
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to parse configuration file: %s\n", err)
+		return 1
+	}
+
+	// Keep the standard output clean if the JSON report goes there
+	var progress io.Writer = os.Stdout
+
+	if configparser.Contains(conf.ReportJSON, "stdout") {
+		progress = os.Stderr
+	}
+
+	fmt.Fprintf(progress, "Parsed configuration file %s\n", opts.config)
+
+	if _, err := runReport(context.Background(), conf, progress); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to send report: %s\n", err)
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,217 @@
+// Package schedule parses cron expressions and calculates the next run
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// field is the allowed range of a cron field
+type field struct {
+	name     string
+	min, max int
+}
+
+var fields = []field{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 6},
+}
+
+// shortcuts are the predefined schedules
+var shortcuts = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Schedule is a parsed cron expression
+type Schedule struct {
+	expr   string
+	minute map[int]bool
+	hour   map[int]bool
+	dom    map[int]bool
+	month  map[int]bool
+	dow    map[int]bool
+
+	// Restricted day fields, see the day-of-month/day-of-week rule in crontab(5)
+	domStar bool
+	dowStar bool
+}
+
+// parseField parses one field: *, */n, a, a-b, a-b/n and comma separated lists of them
+func parseField(value string, f field) (map[int]bool, error) {
+
+	set := make(map[int]bool)
+
+	for _, part := range strings.Split(value, ",") {
+
+		step := 1
+		rangeStr := part
+
+		if i := strings.Index(part, "/"); i != -1 {
+
+			var err error
+
+			rangeStr = part[:i]
+			step, err = strconv.Atoi(part[i+1:])
+
+			if err != nil || step < 1 {
+				return nil, fmt.Errorf("invalid step in %s: %s", f.name, part)
+			}
+		}
+
+		start, end := f.min, f.max
+
+		switch {
+		case rangeStr == "*":
+		case strings.Contains(rangeStr, "-"):
+			bounds := strings.SplitN(rangeStr, "-", 2)
+
+			var err1, err2 error
+
+			start, err1 = strconv.Atoi(bounds[0])
+			end, err2 = strconv.Atoi(bounds[1])
+
+			if err1 != nil || err2 != nil {
+				return nil, fmt.Errorf("invalid range in %s: %s", f.name, part)
+			}
+		default:
+			var err error
+
+			start, err = strconv.Atoi(rangeStr)
+
+			if err != nil {
+				return nil, fmt.Errorf("invalid value in %s: %s", f.name, part)
+			}
+
+			// "5/10" means from 5 to the end with step 10
+			if step == 1 {
+				end = start
+			}
+		}
+
+		// 7 is Sunday too in the day of week
+		if f.name == "day of week" && end == 7 {
+
+			set[0] = true
+			end = 6
+
+			if start == 7 {
+				continue
+			}
+		}
+
+		if start < f.min || end > f.max || start > end {
+			return nil, fmt.Errorf("out of range in %s: %s (%d-%d)", f.name, part, f.min, f.max)
+		}
+
+		for v := start; v <= end; v += step {
+			set[v] = true
+		}
+	}
+
+	return set, nil
+}
+
+// Parse parses a standard 5 fields cron expression (minute hour day-of-month month day-of-week)
+// or a shortcut like @daily or @hourly.
+func Parse(expr string) (*Schedule, error) {
+
+	expr = strings.TrimSpace(expr)
+
+	fieldsStr := strings.Fields(expr)
+
+	if shortcut, ok := shortcuts[expr]; ok {
+		fieldsStr = strings.Fields(shortcut)
+	}
+
+	if len(fieldsStr) != len(fields) {
+		return nil, fmt.Errorf("invalid cron expression: %s: expected %d fields", expr, len(fields))
+	}
+
+	sets := make([]map[int]bool, len(fields))
+
+	for i, f := range fields {
+
+		set, err := parseField(fieldsStr[i], f)
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron expression: %s: %s", expr, err)
+		}
+
+		sets[i] = set
+	}
+
+	return &Schedule{
+		expr:    expr,
+		minute:  sets[0],
+		hour:    sets[1],
+		dom:     sets[2],
+		month:   sets[3],
+		dow:     sets[4],
+		domStar: strings.HasPrefix(fieldsStr[2], "*"),
+		dowStar: strings.HasPrefix(fieldsStr[4], "*")}, nil
+}
+
+// String returns the original expression
+func (s *Schedule) String() string {
+	return s.expr
+}
+
+// dayMatches reports whether the day of t matches.
+// If both day fields are restricted, matching any of them is enough.
+func (s *Schedule) dayMatches(t time.Time) bool {
+
+	domMatch := s.dom[t.Day()]
+	dowMatch := s.dow[int(t.Weekday())]
+
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+
+	return domMatch || dowMatch
+}
+
+// Next returns the first time after t that matches the schedule.
+// Returns zero time if nothing matches in 5 years (eg.: 30th of February).
+func (s *Schedule) Next(t time.Time) time.Time {
+
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+
+		if !s.month[int(t.Month())] {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+
+		if !s.hour[t.Hour()] {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+
+		if !s.minute[t.Minute()] {
+			t = t.Add(time.Minute)
+			continue
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
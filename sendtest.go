@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/g0rbe/vps-sentinel/notify"
+	"github.com/g0rbe/vps-sentinel/report"
+	"github.com/g0rbe/vps-sentinel/sysinfo"
+)
+
+// sendTestCmd sends a small test message with every notifier in report->notify
+func sendTestCmd(args []string) int {
+
+	var opts options
+
+	fs := flag.NewFlagSet("send-test", flag.ExitOnError)
+	opts.register(fs)
+	fs.Parse(args)
+
+	conf, err := opts.loadConfig()
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to parse configuration file: %s\n", err)
+		return 1
+	}
+
+	host := sysinfo.GetFqdn()
+	now := time.Now()
+	text := fmt.Sprintf("This is a test message from vps-sentinel on %s, sent at %s.",
+		host, now.Format(time.RFC1123))
+
+	testReport := report.Report{Host: host, Time: now, Sections: []report.Section{
+		{ID: "test", Title: "Test message", Status: report.StatusOK, Text: text}}}
+
+	status := 0
+
+	for _, n := range notify.FromConfig(conf) {
+
+		fmt.Printf("Sending test message with %s...\n", n.Name())
+
+		switch n := n.(type) {
+		case *notify.SMTP:
+			m := n.NewMessage(fmt.Sprintf("[%s] Test mail from vps-sentinel", host))
+			m.SetBody("text/plain", text+"\n")
+			err = n.Send(context.Background(), m)
+		default:
+			err = n.Notify(context.Background(), testReport)
+		}
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to send test message with %s: %s\n", n.Name(), err)
+			status = 1
+		}
+	}
+
+	return status
+}
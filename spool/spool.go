@@ -0,0 +1,238 @@
+// Package spool keeps the undeliverable mails until they are sent again
+package spool
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/g0rbe/vps-sentinel/internal/atomicfile"
+)
+
+// Backoff limits of the retries, the delay doubles after every failed attempt
+const (
+	MinBackoff = time.Minute
+	MaxBackoff = 6 * time.Hour
+)
+
+// lateFile holds the mails which are delivered after a retry, until they are reported
+const lateFile = "late.json"
+
+// lateLock guards the read-modify-write of lateFile, the retries of the daemon deliver
+// while a report takes the late deliveries. Package level, as a Spool is created for every use.
+var lateLock sync.Mutex
+
+// Message is a spooled mail with its envelope
+type Message struct {
+	ID          string    `json:"id"`
+	From        string    `json:"from"`
+	To          []string  `json:"to"` // Every recipient, including CC and BCC
+	Subject     string    `json:"subject"`
+	Queued      time.Time `json:"queued"`
+	Attempts    int       `json:"attempts"`
+	LastError   string    `json:"last_error"`
+	NextAttempt time.Time `json:"next_attempt"`
+	Delivered   time.Time `json:"delivered,omitempty"`
+	Data        []byte    `json:"data,omitempty"` // The whole mail, with headers
+}
+
+// Due reports whether the message can be sent again at now
+func (m Message) Due(now time.Time) bool {
+	return !now.Before(m.NextAttempt)
+}
+
+// Spool stores the messages in a directory, one file per message
+type Spool struct {
+	Dir string
+}
+
+// New returns a spool in the given directory
+func New(dir string) *Spool {
+	return &Spool{Dir: dir}
+}
+
+// path returns the path of the message's file
+func (s *Spool) path(id string) string {
+	return filepath.Join(s.Dir, id+".msg.json")
+}
+
+// write stores v as JSON in path
+func write(path string, v interface{}) error {
+
+	content, err := json.Marshal(v)
+
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %s", path, err)
+	}
+
+	return atomicfile.Write(path, content, 0600)
+}
+
+// Add stores a message that failed to send with err
+func (s *Spool) Add(from string, to []string, subject string, data []byte, sendErr error) (Message, error) {
+
+	now := time.Now()
+
+	m := Message{
+		ID:          now.Format("20060102T150405.000000000"),
+		From:        from,
+		To:          to,
+		Subject:     subject,
+		Queued:      now,
+		Attempts:    1,
+		LastError:   sendErr.Error(),
+		NextAttempt: now.Add(MinBackoff),
+		Data:        data}
+
+	if err := os.MkdirAll(s.Dir, 0700); err != nil {
+		return m, fmt.Errorf("failed to create %s: %s", s.Dir, err)
+	}
+
+	return m, write(s.path(m.ID), m)
+}
+
+// List returns the spooled messages, from the oldest to the newest
+func (s *Spool) List() ([]Message, error) {
+
+	files, err := ioutil.ReadDir(s.Dir)
+
+	if os.IsNotExist(err) {
+		return []Message{}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %s", s.Dir, err)
+	}
+
+	messages := make([]Message, 0)
+
+	for _, f := range files {
+
+		if !strings.HasSuffix(f.Name(), ".msg.json") {
+			continue
+		}
+
+		m, err := s.Get(strings.TrimSuffix(f.Name(), ".msg.json"))
+
+		if err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, m)
+	}
+
+	// An ID is the time of queueing with nanoseconds, so the order of the IDs is the order of the mails
+	sort.Slice(messages, func(i, j int) bool { return messages[i].ID < messages[j].ID })
+
+	return messages, nil
+}
+
+// Get returns the message with the given ID
+func (s *Spool) Get(id string) (Message, error) {
+
+	var m Message
+
+	content, err := ioutil.ReadFile(s.path(id))
+
+	if err != nil {
+		return m, fmt.Errorf("failed to read message %s: %s", id, err)
+	}
+
+	if err := json.Unmarshal(content, &m); err != nil {
+		return m, fmt.Errorf("failed to parse message %s: %s", id, err)
+	}
+
+	return m, nil
+}
+
+// Failed records another failed attempt and schedules the next one with doubled backoff
+func (s *Spool) Failed(m Message, sendErr error) error {
+
+	backoff := MinBackoff << uint(m.Attempts)
+
+	if backoff > MaxBackoff || backoff <= 0 {
+		backoff = MaxBackoff
+	}
+
+	m.Attempts++
+	m.LastError = sendErr.Error()
+	m.NextAttempt = time.Now().Add(backoff)
+
+	return write(s.path(m.ID), m)
+}
+
+// Delivered removes the message from the spool and keeps it for the late delivery note
+func (s *Spool) Delivered(m Message) error {
+
+	lateLock.Lock()
+	defer lateLock.Unlock()
+
+	late, err := s.late()
+
+	if err != nil {
+		return err
+	}
+
+	m.Attempts++
+	m.Delivered = time.Now()
+	m.Data = nil
+
+	if err := write(filepath.Join(s.Dir, lateFile), append(late, m)); err != nil {
+		return err
+	}
+
+	return s.Drop(m.ID)
+}
+
+// Drop removes the message from the spool
+func (s *Spool) Drop(id string) error {
+
+	if err := os.Remove(s.path(id)); err != nil {
+		return fmt.Errorf("failed to remove message %s: %s", id, err)
+	}
+
+	return nil
+}
+
+// late returns the late deliveries which are not reported yet
+func (s *Spool) late() ([]Message, error) {
+
+	late := make([]Message, 0)
+
+	content, err := ioutil.ReadFile(filepath.Join(s.Dir, lateFile))
+
+	if os.IsNotExist(err) {
+		return late, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %s", lateFile, err)
+	}
+
+	if err := json.Unmarshal(content, &late); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %s", lateFile, err)
+	}
+
+	return late, nil
+}
+
+// TakeLate returns the late deliveries which are not reported yet, and forgets them
+func (s *Spool) TakeLate() ([]Message, error) {
+
+	lateLock.Lock()
+	defer lateLock.Unlock()
+
+	late, err := s.late()
+
+	if err != nil || len(late) == 0 {
+		return late, err
+	}
+
+	if err := os.Remove(filepath.Join(s.Dir, lateFile)); err != nil {
+		return nil, fmt.Errorf("failed to remove %s: %s", lateFile, err)
+	}
+
+	return late, nil
+}
@@ -0,0 +1,144 @@
+// Package state stores the snapshot of the collectors' output between runs
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/g0rbe/vps-sentinel/internal/atomicfile"
+	"github.com/g0rbe/vps-sentinel/report"
+)
+
+// snapshot is the stored output of one collector
+type snapshot struct {
+	Time     time.Time        `json:"time"`
+	Sections []report.Section `json:"sections"`
+}
+
+// Store keeps one snapshot per collector in a directory
+type Store struct {
+	Dir string
+}
+
+// New returns a store in the given directory
+func New(dir string) *Store {
+	return &Store{Dir: dir}
+}
+
+// path returns the path of the collector's snapshot
+func (s *Store) path(collector string) string {
+	return filepath.Join(s.Dir, collector+".json")
+}
+
+// Load returns the stored sections of the collector and the time of the snapshot.
+// Returns false if there is no snapshot yet.
+func (s *Store) Load(collector string) ([]report.Section, time.Time, bool, error) {
+
+	var snap snapshot
+
+	content, err := ioutil.ReadFile(s.path(collector))
+
+	if os.IsNotExist(err) {
+		return nil, snap.Time, false, nil
+	} else if err != nil {
+		return nil, snap.Time, false, fmt.Errorf("failed to read %s: %s", s.path(collector), err)
+	}
+
+	if err := json.Unmarshal(content, &snap); err != nil {
+		return nil, snap.Time, false, fmt.Errorf("failed to parse %s: %s", s.path(collector), err)
+	}
+
+	return snap.Sections, snap.Time, true, nil
+}
+
+// Diff compares every section of the report with the stored snapshots,
+// and sets the changes since the last run.
+func (s *Store) Diff(r *report.Report) error {
+
+	snapshots := make(map[string]snapshot)
+
+	for i := range r.Sections {
+
+		section := &r.Sections[i]
+
+		snap, ok := snapshots[section.Collector]
+
+		if !ok {
+			sections, since, found, err := s.Load(section.Collector)
+
+			if err != nil {
+				return err
+			}
+
+			if !found {
+				continue
+			}
+
+			snap = snapshot{Time: since, Sections: sections}
+			snapshots[section.Collector] = snap
+		}
+
+		for _, prev := range snap.Sections {
+			if prev.ID == section.ID {
+				section.Diff(prev, snap.Time)
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+// Save stores the sections of the report as the snapshot of their collector.
+// Collectors with a failed section are not stored, to keep the last good snapshot.
+func (s *Store) Save(r report.Report) error {
+
+	if err := os.MkdirAll(s.Dir, 0700); err != nil {
+		return fmt.Errorf("failed to create %s: %s", s.Dir, err)
+	}
+
+	snapshots := make(map[string]*snapshot)
+	failed := make(map[string]bool)
+	order := make([]string, 0)
+
+	for _, section := range r.Sections {
+
+		if section.Status != report.StatusOK {
+			failed[section.Collector] = true
+		}
+
+		if _, ok := snapshots[section.Collector]; !ok {
+			snapshots[section.Collector] = &snapshot{Time: r.Time}
+			order = append(order, section.Collector)
+		}
+
+		// Changes belongs to the current run only
+		section.Changes = nil
+
+		snapshots[section.Collector].Sections =
+			append(snapshots[section.Collector].Sections, section)
+	}
+
+	for _, collector := range order {
+
+		if failed[collector] {
+			continue
+		}
+
+		content, err := json.Marshal(snapshots[collector])
+
+		if err != nil {
+			return fmt.Errorf("failed to encode snapshot of %s: %s", collector, err)
+		}
+
+		if err := atomicfile.Write(s.path(collector), content, 0600); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
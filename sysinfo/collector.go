@@ -0,0 +1,43 @@
+package sysinfo
+
+import (
+	"context"
+
+	"github.com/g0rbe/vps-sentinel/collector"
+	"github.com/g0rbe/vps-sentinel/configparser"
+	"github.com/g0rbe/vps-sentinel/report"
+)
+
+// sysCollector is the "system" feature of the report
+type sysCollector struct{}
+
+func init() {
+	collector.Register(sysCollector{})
+}
+
+func (sysCollector) Name() string {
+	return "system"
+}
+
+func (sysCollector) Title() string {
+	return "System informations"
+}
+
+func (sysCollector) Section() string {
+	return "system"
+}
+
+// Collect creates the section of the system informations, the disk usage and the CPU usage
+func (sysCollector) Collect(ctx context.Context, conf configparser.Config) []report.Section {
+
+	section, err := GetSysInfo()
+
+	disk, diskErr := GetDiskUsage()
+
+	cpu, cpuErr := GetCPUUsage(ctx, conf.CPUInterval)
+
+	return []report.Section{
+		collector.NewSection("system", "System informations", section, err),
+		collector.NewSection("system:disk", "Disk usage", disk, diskErr),
+		collector.NewSection("system:cpu", "CPU usage", cpu, cpuErr)}
+}
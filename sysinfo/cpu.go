@@ -0,0 +1,199 @@
+package sysinfo
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/g0rbe/vps-sentinel/report"
+)
+
+// cpuTimes are the times spent in each state of a CPU line of /proc/stat, in USER_HZ.
+// guest and guest_nice are left out, they are already counted in user and nice.
+type cpuTimes struct {
+	user, nice, system, idle, iowait, irq, softirq, steal uint64
+}
+
+// total returns the sum of the times
+func (t cpuTimes) total() uint64 {
+	return t.user + t.nice + t.system + t.idle + t.iowait + t.irq + t.softirq + t.steal
+}
+
+// cpuStat is a sample of /proc/stat
+type cpuStat struct {
+	names []string // "all" and cpu0, cpu1, ... in the order of /proc/stat
+	times map[string]cpuTimes
+}
+
+// getCPUStat reads the cpu lines of /proc/stat
+func getCPUStat() (cpuStat, error) {
+
+	stat := cpuStat{names: make([]string, 0), times: make(map[string]cpuTimes)}
+
+	file, err := os.Open("/proc/stat")
+
+	if err != nil {
+		return stat, fmt.Errorf("failed to open /proc/stat: %s", err)
+	}
+
+	defer file.Close()
+
+	lines := bufio.NewScanner(file)
+
+	for lines.Scan() {
+
+		elems := strings.Fields(lines.Text())
+
+		// Kernels before 2.6.11 have no steal column, it is left 0
+		if len(elems) < 8 || !strings.HasPrefix(elems[0], "cpu") {
+			continue
+		}
+
+		values := make([]uint64, 8)
+
+		for i := range values {
+
+			if i+1 >= len(elems) {
+				break
+			}
+
+			if values[i], err = strconv.ParseUint(elems[i+1], 10, 64); err != nil {
+				return stat, fmt.Errorf("failed to convert %s to int: %s", elems[i+1], err)
+			}
+		}
+
+		name := elems[0]
+
+		if name == "cpu" {
+			name = "all"
+		}
+
+		stat.names = append(stat.names, name)
+		stat.times[name] = cpuTimes{values[0], values[1], values[2], values[3],
+			values[4], values[5], values[6], values[7]}
+	}
+
+	if err := lines.Err(); err != nil {
+		return stat, fmt.Errorf("error while reading /proc/stat: %s", err)
+	}
+
+	return stat, nil
+}
+
+// getOnlineCPUs returns the number of online CPUs from /sys/devices/system/cpu/online,
+// eg.: 0-3,6. Falls back to the number of CPUs usable by this process.
+func getOnlineCPUs() int {
+
+	content, err := ioutil.ReadFile("/sys/devices/system/cpu/online")
+
+	if err != nil {
+		return runtime.NumCPU()
+	}
+
+	count := 0
+
+	for _, r := range strings.Split(strings.TrimSpace(string(content)), ",") {
+
+		bounds := strings.SplitN(r, "-", 2)
+
+		first, err := strconv.Atoi(bounds[0])
+
+		if err != nil {
+			return runtime.NumCPU()
+		}
+
+		last := first
+
+		if len(bounds) == 2 {
+			if last, err = strconv.Atoi(bounds[1]); err != nil || last < first {
+				return runtime.NumCPU()
+			}
+		}
+
+		count += last - first + 1
+	}
+
+	if count == 0 {
+		return runtime.NumCPU()
+	}
+
+	return count
+}
+
+// GetCPUUsage samples /proc/stat twice with the given interval and returns a report
+// with the time spent in each state in percent, in total and per CPU.
+func GetCPUUsage(ctx context.Context, interval time.Duration) (report.Section, error) {
+
+	var section report.Section
+
+	before, err := getCPUStat()
+
+	if err != nil {
+		return section, err
+	}
+
+	select {
+	case <-ctx.Done():
+		return section, ctx.Err()
+	case <-time.After(interval):
+	}
+
+	after, err := getCPUStat()
+
+	if err != nil {
+		return section, err
+	}
+
+	section.Table = report.NewTable(
+		report.Column{Name: "CPU", Type: report.String},
+		report.Column{Name: "User (%)", Type: report.Float},
+		report.Column{Name: "Nice (%)", Type: report.Float},
+		report.Column{Name: "System (%)", Type: report.Float},
+		report.Column{Name: "Idle (%)", Type: report.Float},
+		report.Column{Name: "IOwait (%)", Type: report.Float},
+		report.Column{Name: "IRQ (%)", Type: report.Float},
+		report.Column{Name: "SoftIRQ (%)", Type: report.Float},
+		report.Column{Name: "Steal (%)", Type: report.Float})
+	section.Table.Key = []string{"CPU"}
+
+	for _, name := range after.names {
+
+		// A CPU that went online between the samples has no usage to compare
+		prev, ok := before.times[name]
+
+		if !ok {
+			continue
+		}
+
+		cur := after.times[name]
+
+		// The counters of an offlined CPU may go backwards
+		if cur.total() < prev.total() {
+			continue
+		}
+
+		total := cur.total() - prev.total()
+
+		delta := func(cur, prev uint64) float64 {
+
+			if cur < prev {
+				return 0
+			}
+
+			return percent(cur-prev, total)
+		}
+
+		section.Table.Append(name,
+			delta(cur.user, prev.user), delta(cur.nice, prev.nice), delta(cur.system, prev.system),
+			delta(cur.idle, prev.idle), delta(cur.iowait, prev.iowait), delta(cur.irq, prev.irq),
+			delta(cur.softirq, prev.softirq), delta(cur.steal, prev.steal))
+	}
+
+	return section, nil
+}
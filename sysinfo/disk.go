@@ -0,0 +1,161 @@
+package sysinfo
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/g0rbe/vps-sentinel/report"
+)
+
+// pseudoFilesystems are not backed by a disk, or always full (eg.: squashfs of snaps)
+var pseudoFilesystems = map[string]bool{
+	"autofs": true, "binfmt_misc": true, "bpf": true, "cgroup": true, "cgroup2": true,
+	"configfs": true, "debugfs": true, "devpts": true, "devtmpfs": true, "efivarfs": true,
+	"fusectl": true, "fuse.lxcfs": true, "hugetlbfs": true, "mqueue": true, "nsfs": true,
+	"overlay": true, "proc": true, "pstore": true, "ramfs": true, "rpc_pipefs": true,
+	"securityfs": true, "squashfs": true, "sysfs": true, "tmpfs": true, "tracefs": true,
+}
+
+// mount is an entry of /proc/self/mounts
+type mount struct {
+	device     string
+	mountpoint string
+	fstype     string
+}
+
+// unescape decodes the octal escapes of /proc/self/mounts, eg.: \040 for space
+func unescape(s string) string {
+
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+
+	var b strings.Builder
+
+	for i := 0; i < len(s); i++ {
+
+		if s[i] == '\\' && i+3 < len(s) {
+			if c, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(c))
+				i += 3
+				continue
+			}
+		}
+
+		b.WriteByte(s[i])
+	}
+
+	return b.String()
+}
+
+// getMounts returns the mounted filesystems without the pseudo filesystems
+func getMounts() ([]mount, error) {
+
+	file, err := os.Open("/proc/self/mounts")
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to open /proc/self/mounts: %s", err)
+	}
+
+	defer file.Close()
+
+	mounts := make([]mount, 0)
+
+	lines := bufio.NewScanner(file)
+
+	for lines.Scan() {
+
+		elems := strings.Fields(lines.Text())
+
+		if len(elems) < 3 || pseudoFilesystems[elems[2]] {
+			continue
+		}
+
+		mounts = append(mounts, mount{device: unescape(elems[0]), mountpoint: unescape(elems[1]), fstype: elems[2]})
+	}
+
+	if err := lines.Err(); err != nil {
+		return nil, fmt.Errorf("error while reading /proc/self/mounts: %s", err)
+	}
+
+	return mounts, nil
+}
+
+// percent returns part in percent of total, 0 if total is 0
+func percent(part, total uint64) float64 {
+
+	if total == 0 {
+		return 0
+	}
+
+	return 100 * float64(part) / float64(total)
+}
+
+// GetDiskUsage returns a report with the size, used and available space and the inode usage
+// of every mounted filesystem. A filesystem mounted more than once, eg.: a bind mount,
+// is listed once with its first mountpoint.
+func GetDiskUsage() (report.Section, error) {
+
+	var section report.Section
+
+	mounts, err := getMounts()
+
+	if err != nil {
+		return section, err
+	}
+
+	section.Table = report.NewTable(
+		report.Column{Name: "Mountpoint", Type: report.String},
+		report.Column{Name: "Device", Type: report.String},
+		report.Column{Name: "Type", Type: report.String},
+		report.Column{Name: "Size (GiB)", Type: report.Float},
+		report.Column{Name: "Used (GiB)", Type: report.Float},
+		report.Column{Name: "Available (GiB)", Type: report.Float},
+		report.Column{Name: "Used (%)", Type: report.Float},
+		report.Column{Name: "Inodes used (%)", Type: report.Float})
+	section.Table.Key = []string{"Mountpoint"}
+
+	seen := make(map[uint64]bool)
+
+	for _, m := range mounts {
+
+		var stat syscall.Stat_t
+		var fs syscall.Statfs_t
+
+		// Unreachable mountpoints are skipped, eg.: FUSE of other users
+		if err := syscall.Stat(m.mountpoint, &stat); err != nil {
+			continue
+		}
+
+		if err := syscall.Statfs(m.mountpoint, &fs); err != nil || fs.Blocks == 0 {
+			continue
+		}
+
+		if seen[uint64(stat.Dev)] {
+			continue
+		}
+
+		seen[uint64(stat.Dev)] = true
+
+		blockSize := uint64(fs.Frsize)
+
+		if blockSize == 0 {
+			blockSize = uint64(fs.Bsize)
+		}
+
+		size := fs.Blocks * blockSize
+		used := (fs.Blocks - fs.Bfree) * blockSize
+		available := fs.Bavail * blockSize
+
+		// Like df, the space reserved for root is not counted as available
+		section.Table.Append(m.mountpoint, m.device, m.fstype,
+			float64(size)/1073741824.0, float64(used)/1073741824.0, float64(available)/1073741824.0,
+			percent(used, used+available), percent(fs.Files-fs.Ffree, fs.Files))
+	}
+
+	return section, nil
+}
@@ -8,14 +8,25 @@ import (
 	"os"
 	"strconv"
 	"strings"
+
+	"github.com/g0rbe/vps-sentinel/report"
 )
 
 // MemInfo holds informations about the systems memory
 type memInfo struct {
-	MemTotal  float64
-	MemFree   float64
-	SwapTotal float64
-	SwapFree  float64
+	MemTotal     float64
+	MemFree      float64
+	MemAvailable float64 // Memory available for new processes without swapping, including reclaimable cache
+	Buffers      float64
+	Cached       float64
+	Shmem        float64 // Shared memory and tmpfs, counted in Cached but not reclaimable
+	SReclaimable float64 // Slab that can be reclaimed, eg.: dentry and inode caches
+	Dirty        float64 // Waiting to be written to the disk
+	Writeback    float64 // Being written to the disk
+	CommittedAS  float64 // Memory allocated by the processes, if all of it were used
+	CommitLimit  float64
+	SwapTotal    float64
+	SwapFree     float64
 }
 
 // getMemInfo return a MemInfo struct, holding informations about the systems memory
@@ -24,6 +35,23 @@ func getMemInfo() (memInfo, error) {
 
 	var info memInfo
 
+	// Fields of /proc/meminfo in kB
+	fields := map[string]*float64{
+		"MemTotal:":     &info.MemTotal,
+		"MemFree:":      &info.MemFree,
+		"MemAvailable:": &info.MemAvailable,
+		"Buffers:":      &info.Buffers,
+		"Cached:":       &info.Cached,
+		"Shmem:":        &info.Shmem,
+		"SReclaimable:": &info.SReclaimable,
+		"Dirty:":        &info.Dirty,
+		"Writeback:":    &info.Writeback,
+		"Committed_AS:": &info.CommittedAS,
+		"CommitLimit:":  &info.CommitLimit,
+		"SwapTotal:":    &info.SwapTotal,
+		"SwapFree:":     &info.SwapFree,
+	}
+
 	file, err := os.Open("/proc/meminfo")
 
 	if err != nil {
@@ -33,36 +61,26 @@ func getMemInfo() (memInfo, error) {
 	defer file.Close()
 
 	lines := bufio.NewScanner(file)
+	hasAvailable := false
 
 	for lines.Scan() {
 
 		elems := strings.Fields(lines.Text())
 
-		switch elems[0] {
-		case "MemTotal:":
-			numInKb, err := strconv.ParseFloat(elems[1], 64)
-			if err != nil {
-				return info, fmt.Errorf("failed to convert %s to int: %s", elems[1], err)
-			}
-			info.MemTotal = numInKb * 1024
-		case "MemFree:":
-			numInKb, err := strconv.ParseFloat(elems[1], 64)
-			if err != nil {
-				return info, fmt.Errorf("failed to convert %s to int: %s", elems[1], err)
-			}
-			info.MemFree = numInKb * 1024
-		case "SwapTotal:":
-			numInKb, err := strconv.ParseFloat(elems[1], 64)
-			if err != nil {
-				return info, fmt.Errorf("failed to convert %s to int: %s", elems[1], err)
-			}
-			info.SwapTotal = numInKb * 1024
-		case "SwapFree:":
-			numInKb, err := strconv.ParseFloat(elems[1], 64)
-			if err != nil {
-				return info, fmt.Errorf("failed to convert %s to int: %s", elems[1], err)
-			}
-			info.SwapFree = numInKb * 1024
+		value, ok := fields[elems[0]]
+
+		if !ok || len(elems) < 2 {
+			continue
+		}
+
+		numInKb, err := strconv.ParseFloat(elems[1], 64)
+		if err != nil {
+			return info, fmt.Errorf("failed to convert %s to int: %s", elems[1], err)
+		}
+		*value = numInKb * 1024
+
+		if elems[0] == "MemAvailable:" {
+			hasAvailable = true
 		}
 	}
 
@@ -70,6 +88,11 @@ func getMemInfo() (memInfo, error) {
 		return info, fmt.Errorf("error while reading /proc/meminfo: %s", err)
 	}
 
+	// Kernels before 3.14 have no MemAvailable, estimate it from the reclaimable memory
+	if !hasAvailable {
+		info.MemAvailable = info.MemFree + info.Buffers + info.Cached - info.Shmem + info.SReclaimable
+	}
+
 	return info, nil
 }
 
@@ -177,41 +200,61 @@ func GetFqdn() string {
 }
 
 // GetSysInfo returns a report with system informations
-// Current informations: system load, free/total memory/swap, uptime
-func GetSysInfo() (string, error) {
+// Current informations: system load, load per online CPU, available/free/total memory and its breakdown, free/total swap, uptime
+func GetSysInfo() (report.Section, error) {
+
+	var section report.Section
 
 	loads, err := getSystemLoad()
 
 	if err != nil {
-		return "", fmt.Errorf("failed to get system loads: %s", err)
+		return section, fmt.Errorf("failed to get system loads: %s", err)
 	}
 
 	memInfo, err := getMemInfo()
 
 	if err != nil {
-		return "", fmt.Errorf("failed to get memory informations: %s", err)
+		return section, fmt.Errorf("failed to get memory informations: %s", err)
 	}
 
 	uptime, err := getUpTime()
 
 	if err != nil {
-		return "", fmt.Errorf("failed to get uptime: %s", err)
+		return section, fmt.Errorf("failed to get uptime: %s", err)
 	}
 
-	var report string
-
-	report += fmt.Sprintf("- Average system loads (1/5/15): %.2f, %.2f %.2f\n",
-		loads[0], loads[1], loads[2])
+	cpus := getOnlineCPUs()
 
-	report += fmt.Sprintf("- Free memory: %.2f MiB (total: %.2f MiB)\n",
-		memInfo.MemFree/1048576.0, memInfo.MemTotal/1048576.0)
+	committed := 0.0
 
-	report += fmt.Sprintf("- Free swap: %.2f MiB (total: %.2f MiB)\n",
-		memInfo.SwapFree/1048576.0, memInfo.SwapTotal/1048576.0)
-
-	report += fmt.Sprintf("- Uptime: %.3f day(s)\n", uptime/86400.0)
+	if memInfo.CommitLimit > 0 {
+		committed = 100 * memInfo.CommittedAS / memInfo.CommitLimit
+	}
 
-	report += "\n"
+	section.Fields = []report.Field{
+		{Key: "load1", Label: "Average system load (1 min)", Value: loads[0]},
+		{Key: "load5", Label: "Average system load (5 min)", Value: loads[1]},
+		{Key: "load15", Label: "Average system load (15 min)", Value: loads[2]},
+		{Key: "online_cpus", Label: "Online CPUs", Value: cpus},
+		{Key: "load1_per_cpu", Label: "Average load per CPU (1 min)", Value: loads[0] / float64(cpus)},
+		{Key: "load5_per_cpu", Label: "Average load per CPU (5 min)", Value: loads[1] / float64(cpus)},
+		{Key: "load15_per_cpu", Label: "Average load per CPU (15 min)", Value: loads[2] / float64(cpus)},
+		{Key: "mem_available", Label: "Available memory", Value: memInfo.MemAvailable, Unit: report.Bytes},
+		{Key: "mem_total", Label: "Total memory", Value: memInfo.MemTotal, Unit: report.Bytes},
+		{Key: "mem_free", Label: "Unused memory", Value: memInfo.MemFree, Unit: report.Bytes},
+		{Key: "mem_buffers", Label: "Buffers", Value: memInfo.Buffers, Unit: report.Bytes},
+		{Key: "mem_cached", Label: "Cached", Value: memInfo.Cached, Unit: report.Bytes},
+		{Key: "mem_shmem", Label: "Shared memory (shmem)", Value: memInfo.Shmem, Unit: report.Bytes},
+		{Key: "mem_sreclaimable", Label: "Reclaimable slab", Value: memInfo.SReclaimable, Unit: report.Bytes},
+		{Key: "mem_dirty", Label: "Dirty", Value: memInfo.Dirty, Unit: report.Bytes},
+		{Key: "mem_writeback", Label: "Writeback", Value: memInfo.Writeback, Unit: report.Bytes},
+		{Key: "mem_committed", Label: "Committed memory", Value: memInfo.CommittedAS, Unit: report.Bytes},
+		{Key: "mem_commit_limit", Label: "Commit limit", Value: memInfo.CommitLimit, Unit: report.Bytes},
+		{Key: "mem_committed_percent", Label: "Committed memory of the commit limit", Value: committed, Unit: "%"},
+		{Key: "swap_free", Label: "Free swap", Value: memInfo.SwapFree, Unit: report.Bytes},
+		{Key: "swap_total", Label: "Total swap", Value: memInfo.SwapTotal, Unit: report.Bytes},
+		{Key: "uptime", Label: "Uptime", Value: uptime, Unit: report.Seconds},
+	}
 
-	return report, nil
+	return section, nil
 }